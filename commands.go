@@ -0,0 +1,383 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/donuts-are-good/pig/pkg/pig"
+)
+
+// Exit codes. Flag parsing errors exit with exitUsage as well.
+const (
+	exitFailure  = 1
+	exitUsage    = 2
+	exitNotFound = 3
+	exitFindings = 4
+)
+
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(args []string)
+}
+
+var mailSections = []string{"mx", "spf", "txt", "reverse", "blocklists"}
+
+var axfrSections = []string{"zonetransfer", "amplification", "axfr"}
+
+// reportCommands print a whole report and accept the reportFormats as well
+// as text and json.
+var reportCommands = map[string]bool{"report": true, "mail": true, "axfr": true}
+
+var reportFormats = map[string]bool{"html": true, "markdown": true, "csv": true, "sarif": true}
+
+func commandList() []command {
+	return []command{
+		{"report", "domain", "run every check against a domain and save a snapshot", reportCommand},
+		{"mail", "domain", "check the MX hosts, SPF, DKIM and DMARC records and mail blocklists", mailCommand},
+		{"dnssec", "domain", "show the DNSSEC validation status", dnssecCommand},
+		{"axfr", "domain", "check the nameservers for zone transfers and amplification", axfrCommand},
+		{"ip", "address", "show geolocation, ASN, blocklist and reverse DNS data for an address", ipCommand},
+		{"enum", "domain -w wordlist", "find subdomains from a wordlist", enumCommand},
+		{"diff", "domain [old [new]]", "compare saved snapshots", diffCommand},
+		{"watch", "-f domains.txt [domain...]", "rescan domains on a schedule and send alerts", watchCommand},
+		{"verify", "domain -zone zonefile", "compare the domain's nameservers with a zone file", verifyCommand},
+		{"lint", "zonefile...", "check zone files offline with the same analyzers", lintCommand},
+		{"rules", "", "list the scoring rules and the facts they can test", rulesCommand},
+		{"serve", "[-listen :8080]", "serve the checks over an HTTP API", serveCommand},
+	}
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintln(out, "Usage:", os.Args[0], "[flags] <command> [flags] [arguments]")
+	fmt.Fprintln(out, "      ", os.Args[0], "[flags] domain")
+	fmt.Fprintln(out, "\nCommands:")
+	for _, cmd := range commandList() {
+		fmt.Fprintf(out, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(out, "\nRun", os.Args[0], "help <command> for the flags of a command.")
+	fmt.Fprintln(out, "\nGlobal flags, accepted before or after the command:")
+	flag.PrintDefaults()
+}
+
+func helpCommand(args []string) {
+	if len(args) > 0 {
+		for _, cmd := range commandList() {
+			if cmd.name == args[0] {
+				cmd.run([]string{"-help"})
+				return
+			}
+		}
+		fmt.Fprintln(os.Stderr, "Unknown command:", args[0])
+		usage()
+		os.Exit(exitUsage)
+	}
+	flag.CommandLine.SetOutput(os.Stdout)
+	usage()
+}
+
+func commandFlags(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage:", os.Args[0], strings.TrimSpace(name+" "+args), "[flags]")
+		// The global flags added by parseCommand share their Value with
+		// flag.CommandLine and are listed by pig help instead.
+		own := flag.NewFlagSet(name, flag.ContinueOnError)
+		own.SetOutput(fs.Output())
+		count := 0
+		fs.VisitAll(func(f *flag.Flag) {
+			if global := flag.CommandLine.Lookup(f.Name); global == nil || global.Value != f.Value {
+				own.Var(f.Value, f.Name, f.Usage)
+				own.Lookup(f.Name).DefValue = f.DefValue
+				count++
+			}
+		})
+		if count > 0 {
+			fmt.Fprintln(fs.Output(), "\nFlags:")
+			own.PrintDefaults()
+		}
+		fmt.Fprintln(fs.Output(), "\nRun", os.Args[0], "help for the global flags.")
+	}
+	return fs
+}
+
+// parseCommand adds the global flags the command does not define itself to
+// fs, parses args with flags before or after the positional arguments, and
+// returns the positional ones.
+func parseCommand(fs *flag.FlagSet, args []string) []string {
+	flag.CommandLine.VisitAll(func(f *flag.Flag) {
+		if fs.Lookup(f.Name) == nil {
+			fs.Var(f.Value, f.Name, f.Usage)
+		}
+	})
+	positional := []string{}
+	for {
+		fs.Parse(args)
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	switch {
+	case outputFormat == "text" || outputFormat == "json":
+	case reportFormats[outputFormat] && reportCommands[fs.Name()]:
+	case reportFormats[outputFormat]:
+		fmt.Fprintf(fs.Output(), "-format %s is only supported by the report, mail and axfr commands\n", outputFormat)
+		os.Exit(exitUsage)
+	default:
+		fmt.Fprintf(fs.Output(), "invalid value %q for flag -format: must be text, json, html, markdown, csv or sarif\n", outputFormat)
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	return positional
+}
+
+// sectionFlags adds -only and -skip to fs. The returned function gives the
+// sections to run: defaults, or the -only list, minus the -skip list. Nil
+// defaults are every section the scan profile allows. Asking for an active
+// section without the active profile is a usage error.
+func sectionFlags(fs *flag.FlagSet, defaults []string) func() []string {
+	usage := "comma-separated sections to run instead of "
+	if defaults == nil {
+		usage += "every section the profile allows"
+	} else {
+		usage += strings.Join(defaults, ",")
+	}
+	only := fs.String("only", "", usage)
+	skip := fs.String("skip", "", "comma-separated sections to leave out")
+	return func() []string {
+		profile := scanProfile()
+		sections := defaults
+		if sections == nil {
+			sections = []string{}
+			for _, section := range pig.Sections {
+				if profile == pig.Active || !pig.IsActiveSection(section) {
+					sections = append(sections, section)
+				}
+			}
+		}
+		if *only != "" {
+			sections = splitSections(fs, *only)
+		}
+		skipped := map[string]bool{}
+		if *skip != "" {
+			for _, section := range splitSections(fs, *skip) {
+				skipped[section] = true
+			}
+		}
+		selected := []string{}
+		for _, section := range sections {
+			if skipped[section] {
+				continue
+			}
+			if pig.IsActiveSection(section) && profile != pig.Active {
+				fmt.Fprintf(fs.Output(), "section %s sends probes to the domain's nameservers, pass -active to allow it\n", section)
+				os.Exit(exitUsage)
+			}
+			selected = append(selected, section)
+		}
+		if len(selected) < 1 {
+			fmt.Fprintln(fs.Output(), "no sections left to run")
+			os.Exit(exitUsage)
+		}
+		return selected
+	}
+}
+
+func splitSections(fs *flag.FlagSet, list string) []string {
+	known := map[string]bool{}
+	for _, section := range pig.Sections {
+		known[section] = true
+	}
+	sections := []string{}
+	for _, section := range strings.Split(list, ",") {
+		section = strings.ToLower(strings.TrimSpace(section))
+		if !known[section] {
+			fmt.Fprintf(fs.Output(), "unknown section %q, expected one of: %s\n", section, strings.Join(pig.Sections, ", "))
+			os.Exit(exitUsage)
+		}
+		sections = append(sections, section)
+	}
+	return sections
+}
+
+// domainArg returns the single domain argument of a command, lower-cased
+// and without a trailing dot, or exits with a usage error.
+func domainArg(fs *flag.FlagSet, args []string) string {
+	if len(args) != 1 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	return strings.TrimSuffix(strings.ToLower(args[0]), ".")
+}
+
+func printJSON(v interface{}) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fmt.Fprintln(os.Stderr, "Error writing JSON:", err)
+		os.Exit(exitFailure)
+	}
+}
+
+func reportCommand(args []string) {
+	fs := commandFlags("report", "domain")
+	sections := sectionFlags(fs, nil)
+	domain := domainArg(fs, parseCommand(fs, args))
+
+	selected := sections()
+	scanner := newScanner()
+	report := scanner.Report(domain, selected...)
+	showReport(scanner, report)
+	if !noSnapshot {
+		saveReport(report)
+	}
+	if len(report.Records) < 1 {
+		os.Exit(exitNotFound)
+	}
+}
+
+func mailCommand(args []string) {
+	fs := commandFlags("mail", "domain")
+	sections := sectionFlags(fs, mailSections)
+	domain := domainArg(fs, parseCommand(fs, args))
+
+	selected := sections()
+	scanner := newScanner()
+	report := scanner.Report(domain, selected...)
+	showReport(scanner, report)
+	if len(report.Records) < 1 {
+		os.Exit(exitNotFound)
+	}
+}
+
+func axfrCommand(args []string) {
+	fs := commandFlags("axfr", "domain")
+	sections := sectionFlags(fs, axfrSections)
+	domain := domainArg(fs, parseCommand(fs, args))
+
+	selected := sections()
+	scanner := newScanner()
+	report := scanner.Report(domain, selected...)
+	showReport(scanner, report)
+	if len(report.Records) < 1 {
+		os.Exit(exitNotFound)
+	}
+}
+
+func showReport(scanner *pig.Scanner, report *pig.Report) {
+	switch outputFormat {
+	case "json":
+		printJSON(report)
+		return
+	case "html", "markdown", "csv", "sarif":
+		var err error
+		switch outputFormat {
+		case "csv":
+			err = writeCSV(os.Stdout, report)
+		case "sarif":
+			err = writeSARIF(os.Stdout, scanner, report)
+		default:
+			err = renderReport(os.Stdout, outputFormat, report)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error writing report:", err)
+			os.Exit(exitFailure)
+		}
+		return
+	}
+	printReport(scanner, report)
+	if len(report.Records) < 1 {
+		fmt.Println("\nNo DNS records found for", report.Domain)
+	}
+}
+
+func saveReport(report *pig.Report) {
+	out := os.Stdout
+	if outputFormat != "text" {
+		out = os.Stderr
+	}
+	store, err := snapshotStore()
+	if err == nil {
+		var path string
+		if path, err = store.Save(report); err == nil {
+			fmt.Fprintln(out, "\nSnapshot saved to", path)
+			return
+		}
+	}
+	fmt.Fprintln(out, "Error saving snapshot:", err)
+}
+
+func dnssecCommand(args []string) {
+	fs := commandFlags("dnssec", "domain")
+	domain := domainArg(fs, parseCommand(fs, args))
+
+	log := &pig.LookupLog{}
+	status := newScanner().WithLog(log).DNSSEC(domain)
+	if outputFormat == "json" {
+		printJSON(status)
+	} else {
+		printDNSSEC(status)
+		printLookups(log.Lookups())
+	}
+	if status.Status == "unknown" {
+		os.Exit(exitFailure)
+	}
+}
+
+func ipCommand(args []string) {
+	fs := commandFlags("ip", "address")
+	positional := parseCommand(fs, args)
+	if len(positional) != 1 || net.ParseIP(positional[0]) == nil {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	ip := net.ParseIP(positional[0])
+	if ipv4 := ip.To4(); ipv4 != nil {
+		ip = ipv4
+	}
+
+	log := &pig.LookupLog{}
+	info := newScanner().WithLog(log).Address(ip)
+	if outputFormat == "json" {
+		printJSON(info)
+		return
+	}
+	fmt.Printf("\n[%s]\n", ip)
+	printAddress(info)
+	fmt.Println("\n[Reverse DNS]")
+	printFCrDNS(*info.Reverse)
+	printLookups(log.Lookups())
+}
+
+func rulesCommand(args []string) {
+	fs := commandFlags("rules", "")
+	if len(parseCommand(fs, args)) > 0 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	rules := newScanner().Rules()
+	if outputFormat == "json" {
+		printJSON(rules)
+		return
+	}
+	for _, category := range pig.Categories {
+		fmt.Printf("\n[%s Rules]\n", strings.ToUpper(category))
+		for _, rule := range rules {
+			if rule.Category == category {
+				fmt.Printf("%s (%s): %s\n", rule.ID, rule.Severity, rule.Title)
+			}
+		}
+	}
+	fmt.Println("\n[Facts]")
+	fmt.Println(strings.Join(pig.Facts(), ", "))
+}
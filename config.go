@@ -0,0 +1,173 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/donuts-are-good/pig/pkg/pig"
+)
+
+// configPaths returns ~/.config/pig/<name> when it exists, followed by the
+// files given on the command line, in the order they should be applied.
+func configPaths(name string, overrides []string) []string {
+	paths := []string{}
+	if dir, err := os.UserConfigDir(); err == nil {
+		path := filepath.Join(dir, "pig", name)
+		if _, err := os.Stat(path); err == nil {
+			paths = append(paths, path)
+		}
+	}
+	return append(paths, overrides...)
+}
+
+var (
+	probeHTTP        bool
+	resolver         string
+	resolverCA       string
+	queryTimeout     time.Duration
+	outputFormat     = "text"
+	verbose          bool
+	profileName      string
+	activeConsent    bool
+	serviceOverrides []string
+	dnsblOverrides   []string
+	ruleOverrides    []string
+	pdnsFiles        []string
+	geoBackend       = "mmdb"
+	geoIPPaths       []string
+	ipinfoToken      = os.Getenv("IPINFO_TOKEN")
+	templateFile     string
+	sarifURI         string
+	snapshotDir      string
+	noSnapshot       bool
+)
+
+// newScanner builds a scanner from the global flags and the files in
+// ~/.config/pig.
+func newScanner() *pig.Scanner {
+	opts := pig.Options{
+		Resolver:        resolver,
+		Timeout:         queryTimeout,
+		ProbeHTTP:       probeHTTP,
+		Profile:         scanProfile(),
+		ServiceFiles:    configPaths("services.json", serviceOverrides),
+		DNSBLFiles:      configPaths("dnsbl.json", dnsblOverrides),
+		RuleFiles:       configPaths("rules.json", ruleOverrides),
+		PassiveDNSFiles: pdnsFiles,
+	}
+	if resolverCA != "" {
+		pem, err := os.ReadFile(resolverCA)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error reading resolver CA:", err)
+			os.Exit(exitFailure)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			fmt.Fprintln(os.Stderr, "Error reading resolver CA: no certificates in", resolverCA)
+			os.Exit(exitFailure)
+		}
+		opts.TLSConfig = &tls.Config{RootCAs: pool}
+	}
+	opts.Geo = &lazyGeo{}
+
+	scanner, err := pig.NewScanner(opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(exitFailure)
+	}
+	return scanner
+}
+
+// lazyGeo sets up the -geo backend the first time an address is located,
+// so commands that never geolocate neither open the database nor warn
+// about it. A backend that cannot be set up is reported once and left out
+// rather than stopping the scan.
+type lazyGeo struct {
+	once sync.Once
+	geo  pig.GeoProvider
+}
+
+func (l *lazyGeo) Locate(ip net.IP) (*pig.Geolocation, error) {
+	l.once.Do(func() { l.geo = geoProvider() })
+	if l.geo == nil {
+		return nil, nil
+	}
+	return l.geo.Locate(ip)
+}
+
+func geoProvider() pig.GeoProvider {
+	switch geoBackend {
+	case "mmdb":
+		geo, err := pig.NewMMDBProvider(geoIPPaths)
+		if err == nil {
+			return geo
+		}
+		if errors.Is(err, pig.ErrNoGeoIPDatabase) {
+			fmt.Fprintln(os.Stderr, "Geolocation disabled:", err.Error()+", pass one with -geoip or use -geo ipinfo")
+		} else {
+			fmt.Fprintln(os.Stderr, "Geolocation disabled:", err)
+		}
+	case "ipinfo":
+		return pig.NewIPInfoProvider(ipinfoToken)
+	default:
+		fmt.Fprintf(os.Stderr, "Geolocation disabled: unknown geolocation backend %q\n", geoBackend)
+	}
+	return nil
+}
+
+// scanProfile returns the -profile flag, which defaults to active when
+// -active is given and standard otherwise. The active profile needs -active
+// as well, and the passive one cannot fetch pages with -http.
+func scanProfile() pig.Profile {
+	profile := pig.Profile(profileName)
+	if profile == "" {
+		profile = pig.Standard
+		if activeConsent {
+			profile = pig.Active
+		}
+	}
+	switch {
+	case profile != pig.Passive && profile != pig.Standard && profile != pig.Active:
+		fmt.Fprintf(os.Stderr, "Unknown scan profile %q, expected passive, standard or active\n", profile)
+		os.Exit(exitUsage)
+	case profile == pig.Active && !activeConsent:
+		fmt.Fprintln(os.Stderr, "The active profile sends zone transfer, TCP and DNS over TLS probes to the domain's nameservers, and amplification queries. Pass -active to allow them.")
+		os.Exit(exitUsage)
+	case profile == pig.Passive && probeHTTP:
+		fmt.Fprintln(os.Stderr, "-http fetches pages from the domain's hosts and cannot be used with the passive profile.")
+		os.Exit(exitUsage)
+	}
+	return profile
+}
+
+// snapshotStore returns the -store directory, or the default location.
+func snapshotStore() (*pig.Store, error) {
+	if snapshotDir != "" {
+		return &pig.Store{Dir: snapshotDir}, nil
+	}
+	dir, err := pig.DefaultStoreDir()
+	if err != nil {
+		return nil, err
+	}
+	return &pig.Store{Dir: dir}, nil
+}
+
+// pathList is a comma-separated list flag.
+type pathList []string
+
+func (l *pathList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *pathList) Set(value string) error {
+	*l = strings.Split(value, ",")
+	return nil
+}
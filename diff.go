@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/donuts-are-good/pig/pkg/pig"
+)
+
+func printDiff(d *pig.ReportDiff) {
+	fmt.Printf("\n[Changes for %s]\n", d.Domain)
+	fmt.Printf("%s -> %s\n", d.Old.Format(time.RFC3339), d.New.Format(time.RFC3339))
+	if d.Empty() {
+		fmt.Println("No changes")
+		return
+	}
+
+	if len(d.Added) > 0 || len(d.Removed) > 0 || len(d.Changed) > 0 {
+		fmt.Println("\n[Records]")
+		for _, rec := range d.Added {
+			fmt.Printf("+  %s %s %d %s\n", rec.Name, rec.Type, rec.TTL, rec.Data)
+		}
+		for _, rec := range d.Removed {
+			fmt.Printf("-  %s %s %d %s\n", rec.Name, rec.Type, rec.TTL, rec.Data)
+		}
+		for _, c := range d.Changed {
+			fmt.Printf("~  %s %s: %s -> %s\n", c.New.Name, c.New.Type, c.Old.Data, c.New.Data)
+		}
+	}
+
+	if len(d.NSAdded) > 0 || len(d.NSRemoved) > 0 {
+		fmt.Println("\n[Nameservers]")
+		for _, ns := range d.NSAdded {
+			fmt.Println("+  " + ns)
+		}
+		for _, ns := range d.NSRemoved {
+			fmt.Println("-  " + ns)
+		}
+	}
+
+	if len(d.OldSPF) > 0 || len(d.NewSPF) > 0 {
+		fmt.Println("\n[SPF]")
+		for _, spf := range d.OldSPF {
+			fmt.Println("-  " + spf)
+		}
+		for _, spf := range d.NewSPF {
+			fmt.Println("+  " + spf)
+		}
+	}
+
+	if len(d.Listed) > 0 || len(d.Delisted) > 0 {
+		fmt.Println("\n[Blocklists]")
+		for _, res := range d.Listed {
+			fmt.Printf("+  %s is listed on %s (%s): %s\n", res.Target, res.List, res.Zone, strings.Join(res.Reasons, ", "))
+		}
+		for _, res := range d.Delisted {
+			fmt.Printf("-  %s is no longer listed on %s (%s)\n", res.Target, res.List, res.Zone)
+		}
+	}
+}
+
+func diffCommand(args []string) {
+	fs := commandFlags("diff", "domain [old [new]]")
+	list := fs.Bool("list", false, "list the saved snapshots of the domain")
+	refs := parseCommand(fs, args)
+	if len(refs) < 1 || len(refs) > 3 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	domain := strings.TrimSuffix(strings.ToLower(refs[0]), ".")
+	refs = refs[1:]
+
+	store, err := snapshotStore()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error reading snapshots:", err)
+		os.Exit(exitFailure)
+	}
+	ids, err := store.List(domain)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error reading snapshots:", err)
+		os.Exit(exitFailure)
+	}
+	if *list {
+		for _, id := range ids {
+			fmt.Println(id)
+		}
+		return
+	}
+
+	// Missing references default to the two most recent snapshots.
+	switch len(refs) {
+	case 0:
+		if len(ids) < 2 {
+			fmt.Fprintf(os.Stderr, "Need two snapshots of %s to compare, found %d\n", domain, len(ids))
+			os.Exit(exitNotFound)
+		}
+		refs = ids[len(ids)-2:]
+	case 1:
+		if len(ids) < 1 {
+			fmt.Fprintf(os.Stderr, "No snapshots of %s found\n", domain)
+			os.Exit(exitNotFound)
+		}
+		refs = append(refs, ids[len(ids)-1])
+	}
+
+	old, err := store.Load(domain, refs[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error loading snapshot:", err)
+		os.Exit(loadFailure(err))
+	}
+	cur, err := store.Load(domain, refs[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error loading snapshot:", err)
+		os.Exit(loadFailure(err))
+	}
+	if outputFormat == "json" {
+		printJSON(pig.Diff(old, cur))
+		return
+	}
+	printDiff(pig.Diff(old, cur))
+}
+
+// loadFailure exits with exitNotFound for a snapshot that does not exist.
+func loadFailure(err error) int {
+	if errors.Is(err, fs.ErrNotExist) {
+		return exitNotFound
+	}
+	return exitFailure
+}
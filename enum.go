@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/donuts-are-good/pig/pkg/pig"
+)
+
+// enumHost is a discovered host with the checks run on it, the same ones
+// the text output prints.
+type enumHost struct {
+	pig.EnumResult
+	Service   string              `json:"service,omitempty"`
+	Takeover  *pig.TakeoverResult `json:"takeover,omitempty"`
+	Addresses []*pig.AddressInfo  `json:"addresses,omitempty"`
+}
+
+func enumCommand(args []string) {
+	fs := commandFlags("enum", "domain -w wordlist")
+	wordlist := fs.String("w", "", "wordlist of subdomain labels, one per line")
+	workers := fs.Int("c", 20, "number of concurrent resolvers")
+	rate := fs.Int("r", 50, "maximum queries per second")
+	domain := domainArg(fs, parseCommand(fs, args))
+	if *wordlist == "" {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	labels, err := readWordlist(*wordlist)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error reading wordlist:", err)
+		os.Exit(exitFailure)
+	}
+
+	scanner := newScanner()
+	wc := scanner.DetectWildcard(domain)
+	results := []enumHost{}
+	for _, res := range scanner.Enumerate(domain, labels, *workers, *rate) {
+		if !wc.Matches(res) {
+			results = append(results, checkHost(scanner, res))
+		}
+	}
+	if outputFormat == "json" {
+		printJSON(results)
+		return
+	}
+
+	fmt.Println("\n[Subdomain Enumeration]")
+	if len(wc.IPs) > 0 || len(wc.CNAMEs) > 0 {
+		fmt.Println("Wildcard DNS detected, filtering matching answers:")
+		for ip := range wc.IPs {
+			fmt.Println("-  " + ip)
+		}
+		for cname := range wc.CNAMEs {
+			fmt.Println("-  " + cname)
+		}
+	} else {
+		fmt.Println("No wildcard DNS detected")
+	}
+
+	for _, host := range results {
+		printEnumHost(scanner, host)
+	}
+	fmt.Printf("\nResolved %d of %d candidates\n", len(results), len(labels))
+}
+
+func readWordlist(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	seen := make(map[string]bool)
+	labels := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		label := strings.ToLower(strings.Trim(strings.TrimSpace(scanner.Text()), "."))
+		if label == "" || strings.HasPrefix(label, "#") || seen[label] {
+			continue
+		}
+		seen[label] = true
+		labels = append(labels, label)
+	}
+	return labels, scanner.Err()
+}
+
+func checkHost(scanner *pig.Scanner, res pig.EnumResult) enumHost {
+	host := enumHost{EnumResult: res}
+	if res.CNAME != "" {
+		host.Service = scanner.DetectService(res.CNAME)
+		chain, _ := scanner.CNAMEChain(res.Host)
+		if takeover := scanner.Takeover(chain); takeover != nil && takeover.Risk != "None" {
+			host.Takeover = takeover
+		}
+	}
+	for _, ip := range res.IPs {
+		if ipv4 := ip.To4(); ipv4 != nil {
+			ip = ipv4
+		}
+		host.Addresses = append(host.Addresses, scanner.Address(ip))
+	}
+	return host
+}
+
+func printEnumHost(scanner *pig.Scanner, host enumHost) {
+	fmt.Printf("\n[%s]\n", host.Host)
+	if host.CNAME != "" {
+		fmt.Printf("CNAME: %s (%s)\n", host.CNAME, host.Service)
+		if host.Takeover != nil {
+			printTakeover(scanner, host.Takeover)
+		}
+	}
+	for _, info := range host.Addresses {
+		if info.IP.To4() != nil {
+			fmt.Println(info.IP.String())
+		} else {
+			fmt.Println("AAAA: " + info.IP.String())
+		}
+		printAddress(info)
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/donuts-are-good/pig/pkg/pig"
+)
+
+var csvHeader = []string{"domain", "kind", "name", "type", "ttl", "data", "id", "category", "severity", "title", "remediation"}
+
+// writeCSV writes one row per record of the report followed by one row per
+// finding. Records fill name, type, ttl and data, and findings fill the
+// columns from id on, with their evidence in data.
+func writeCSV(out io.Writer, report *pig.Report) error {
+	w := csv.NewWriter(out)
+	w.Write(csvHeader)
+	for _, rec := range report.Records {
+		w.Write([]string{report.Domain, "record", rec.Name, rec.Type, strconv.FormatUint(uint64(rec.TTL), 10), rec.Data, "", "", "", "", ""})
+	}
+	if report.Score != nil {
+		for _, f := range report.Score.Findings {
+			w.Write([]string{report.Domain, "finding", report.Domain, "", "", strings.Join(f.Evidence, "; "), f.ID, f.Category, string(f.Severity), f.Title, f.Remediation})
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	Name                 string             `json:"name"`
+	ShortDescription     sarifText          `json:"shortDescription"`
+	FullDescription      sarifText          `json:"fullDescription"`
+	Help                 *sarifText         `json:"help,omitempty"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+	Properties           sarifProperties    `json:"properties"`
+}
+
+type sarifText struct {
+	Text string `json:"text"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifProperties struct {
+	Tags             []string `json:"tags"`
+	SecuritySeverity string   `json:"security-severity"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	RuleIndex           int               `json:"ruleIndex"`
+	Level               string            `json:"level"`
+	Message             sarifText         `json:"message"`
+	Locations           []sarifLocation   `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation,omitempty"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type sarifLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// sarifLevels maps finding severities to SARIF levels and to the
+// security-severity scores GitHub code scanning ranks alerts by.
+var sarifLevels = map[pig.Severity]struct{ level, score string }{
+	pig.SeverityCritical: {"error", "9.5"},
+	pig.SeverityHigh:     {"error", "7.5"},
+	pig.SeverityMedium:   {"warning", "5.0"},
+	pig.SeverityLow:      {"note", "3.0"},
+	pig.SeverityInfo:     {"note", "0.0"},
+}
+
+// writeSARIF writes the findings of the report as a SARIF 2.1.0 log. Every
+// rule of the scanner is listed under its pig rule ID so results keep their
+// IDs across runs. Results are located at the domain, and at line 1 of
+// -sarif-uri, the file the domain is managed in. Without -sarif-uri the
+// domain itself is the artifact, since code scanning drops results that
+// have no physical location.
+func writeSARIF(out io.Writer, scanner *pig.Scanner, report *pig.Report) error {
+	driver := sarifDriver{Name: "pig", InformationURI: "https://github.com/donuts-are-good/pig", Rules: []sarifRule{}}
+	index := map[string]int{}
+	for _, rule := range scanner.Rules() {
+		index[rule.ID] = len(driver.Rules)
+		r := sarifRule{
+			ID:                   rule.ID,
+			Name:                 rule.ID,
+			ShortDescription:     sarifText{rule.Title},
+			FullDescription:      sarifText{rule.Description},
+			DefaultConfiguration: sarifConfiguration{sarifLevels[rule.Severity].level},
+			Properties:           sarifProperties{Tags: []string{"security", rule.Category}, SecuritySeverity: sarifLevels[rule.Severity].score},
+		}
+		if rule.Remediation != "" {
+			r.Help = &sarifText{rule.Remediation}
+		}
+		driver.Rules = append(driver.Rules, r)
+	}
+
+	uri := sarifURI
+	if uri == "" {
+		uri = report.Domain
+	}
+	results := []sarifResult{}
+	if report.Score != nil {
+		for _, f := range report.Score.Findings {
+			i, ok := index[f.ID]
+			if !ok {
+				continue
+			}
+			message := f.Title + " on " + report.Domain + ". " + f.Description
+			if len(f.Evidence) > 0 {
+				message += " Evidence: " + strings.Join(f.Evidence, "; ") + "."
+			}
+			location := sarifLocation{LogicalLocations: []sarifLogicalLocation{{Name: report.Domain, FullyQualifiedName: report.Domain, Kind: "domain"}}}
+			location.PhysicalLocation = &sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: uri}, Region: sarifRegion{StartLine: 1}}
+			sum := sha256.Sum256([]byte(f.ID + "\x00" + report.Domain))
+			results = append(results, sarifResult{
+				RuleID:              f.ID,
+				RuleIndex:           i,
+				Level:               sarifLevels[f.Severity].level,
+				Message:             sarifText{message},
+				Locations:           []sarifLocation{location},
+				PartialFingerprints: map[string]string{"pigFinding/v1": hex.EncodeToString(sum[:16])},
+			})
+		}
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	})
+}
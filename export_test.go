@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/donuts-are-good/pig/pkg/pig"
+)
+
+func TestWriteSARIFLocation(t *testing.T) {
+	scanner, err := pig.NewScanner(pig.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := &pig.Report{Domain: "example.com", Score: &pig.Scorecard{Findings: []pig.Finding{
+		{ID: "spf-missing", Severity: pig.SeverityMedium, Title: "No SPF record"},
+	}}}
+
+	saved := sarifURI
+	defer func() { sarifURI = saved }()
+	for _, tt := range []struct{ flag, want string }{
+		{"", "example.com"},
+		{"dns/example.com.zone", "dns/example.com.zone"},
+	} {
+		sarifURI = tt.flag
+		var buf bytes.Buffer
+		if err := writeSARIF(&buf, scanner, report); err != nil {
+			t.Fatal(err)
+		}
+		var log sarifLog
+		if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+			t.Fatal(err)
+		}
+		results := log.Runs[0].Results
+		if len(results) != 1 || results[0].RuleID != "spf-missing" {
+			t.Fatalf("-sarif-uri %q: results %+v, want one spf-missing result", tt.flag, results)
+		}
+		loc := results[0].Locations[0]
+		if loc.PhysicalLocation == nil || loc.PhysicalLocation.ArtifactLocation.URI != tt.want || loc.PhysicalLocation.Region.StartLine != 1 {
+			t.Errorf("-sarif-uri %q: physical location %+v, want %s line 1", tt.flag, loc.PhysicalLocation, tt.want)
+		}
+		if len(loc.LogicalLocations) != 1 || loc.LogicalLocations[0].Name != "example.com" {
+			t.Errorf("-sarif-uri %q: logical locations %+v", tt.flag, loc.LogicalLocations)
+		}
+	}
+}
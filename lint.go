@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/donuts-are-good/pig/pkg/pig"
+)
+
+type lintResult struct {
+	File    string          `json:"file"`
+	Origin  string          `json:"origin"`
+	Records int             `json:"records"`
+	Issues  []pig.LintIssue `json:"issues"`
+}
+
+func lintCommand(args []string) {
+	fs := commandFlags("lint", "zonefile...")
+	origin := fs.String("origin", "", "origin of the zone (default the file's $ORIGIN or SOA, or its name such as db.example.com)")
+	failOn := fs.String("fail-on", "medium", "lowest severity that makes pig exit with status 4, or none")
+	files := parseCommand(fs, args)
+	if len(files) < 1 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	var threshold pig.Severity
+	if *failOn != "none" {
+		var err error
+		if threshold, err = pig.ParseSeverity(*failOn); err != nil {
+			fmt.Fprintln(fs.Output(), "invalid value for flag -fail-on:", err)
+			os.Exit(exitUsage)
+		}
+	}
+
+	scanner := newScanner()
+	results := []lintResult{}
+	failed := false
+	for _, file := range files {
+		name := *origin
+		if name == "" {
+			name = zoneName(file)
+		}
+		zone, err := pig.ParseZoneFile(file, name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error reading zone file:", err)
+			os.Exit(exitFailure)
+		}
+		result := lintResult{File: file, Origin: zone.Origin, Records: len(zone.Records), Issues: scanner.Lint(zone)}
+		for _, issue := range result.Issues {
+			if threshold != "" && issue.Severity.AtLeast(threshold) {
+				failed = true
+			}
+		}
+		results = append(results, result)
+	}
+
+	if outputFormat == "json" {
+		printJSON(results)
+	} else {
+		for _, result := range results {
+			printLint(result)
+		}
+	}
+	if failed {
+		os.Exit(exitFindings)
+	}
+}
+
+// zoneName guesses the origin of a zone from its file name, as in
+// db.example.com or example.com.zone. It returns "" when the name does not
+// look like a domain, leaving the origin to the file.
+func zoneName(file string) string {
+	name := strings.ToLower(filepath.Base(file))
+	name = strings.TrimPrefix(name, "db.")
+	for _, suffix := range []string{".zone", ".db"} {
+		name = strings.TrimSuffix(name, suffix)
+	}
+	if !strings.Contains(name, ".") {
+		return ""
+	}
+	return name
+}
+
+func printLint(result lintResult) {
+	fmt.Printf("\n[Lint %s]\n", result.File)
+	if result.Origin != "" {
+		fmt.Printf("Origin %s, %d records\n", strings.TrimSuffix(result.Origin, "."), result.Records)
+	} else {
+		fmt.Printf("No origin, %d records. Use -origin to set one.\n", result.Records)
+	}
+	counts := map[pig.Severity]int{}
+	for _, issue := range result.Issues {
+		counts[issue.Severity]++
+		name := ""
+		if issue.Name != "" {
+			name = " " + strings.TrimSuffix(issue.Name, ".")
+		}
+		fmt.Printf("%s:%d: %s [%s]%s: %s\n", issue.File, issue.Line, strings.ToUpper(string(issue.Severity)), issue.ID, name, issue.Title)
+		if issue.Detail != "" {
+			fmt.Printf("-  %s\n", issue.Detail)
+		}
+		if issue.Remediation != "" {
+			fmt.Printf("-  Fix: %s\n", issue.Remediation)
+		}
+	}
+	if len(result.Issues) < 1 {
+		fmt.Println("No issues found")
+		return
+	}
+	summary := []string{}
+	for _, severity := range []pig.Severity{pig.SeverityCritical, pig.SeverityHigh, pig.SeverityMedium, pig.SeverityLow, pig.SeverityInfo} {
+		if counts[severity] > 0 {
+			summary = append(summary, fmt.Sprintf("%d %s", counts[severity], severity))
+		}
+	}
+	fmt.Printf("\n%d %s: %s\n", len(result.Issues), plural(len(result.Issues), "issue", "issues"), strings.Join(summary, ", "))
+}
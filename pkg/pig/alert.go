@@ -0,0 +1,82 @@
+package pig
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Alert struct {
+	Domain  string    `json:"domain"`
+	Kind    string    `json:"kind"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+// Alerts compares two scans of a domain and returns the changes worth
+// telling someone about.
+func Alerts(old, cur *Report) []Alert {
+	alerts := []Alert{}
+	add := func(kind, format string, args ...interface{}) {
+		alerts = append(alerts, Alert{Domain: cur.Domain, Kind: kind, Message: fmt.Sprintf(format, args...), Time: cur.Time})
+	}
+	d := Diff(old, cur)
+
+	if len(d.NSAdded) > 0 || len(d.NSRemoved) > 0 {
+		add("ns", "nameservers changed from %s to %s", listOrNone(old.Nameservers()), listOrNone(cur.Nameservers()))
+	}
+	oldMX, curMX := mxHosts(old), mxHosts(cur)
+	if added, removed := diffStrings(oldMX, curMX); len(added) > 0 || len(removed) > 0 {
+		add("mx", "mail exchangers changed from %s to %s", listOrNone(oldMX), listOrNone(curMX))
+	}
+	if len(d.OldSPF) > 0 || len(d.NewSPF) > 0 {
+		add("spf", "SPF changed from %s to %s", listOrNone(d.OldSPF), listOrNone(d.NewSPF))
+	}
+	for _, res := range d.Listed {
+		add("blocklist", "%s is listed on %s (%s): %s", res.Target, res.List, res.Zone, strings.Join(res.Reasons, ", "))
+	}
+	if cur.DNSSEC != nil && cur.DNSSEC.Status == "bogus" && (old.DNSSEC == nil || old.DNSSEC.Status != "bogus") {
+		add("dnssec", "DNSSEC validation is failing: %s", cur.DNSSEC.Detail)
+	}
+	if (old.Has("zonetransfer") && cur.Has("zonetransfer")) || (old.Has("axfr") && cur.Has("axfr")) {
+		opened, _ := diffStrings(old.transferServers(), cur.transferServers())
+		for _, server := range opened {
+			add("zone_transfer", "%s now allows zone transfers", server)
+		}
+	}
+	return alerts
+}
+
+func listOrNone(values []string) string {
+	if len(values) == 0 {
+		return "none"
+	}
+	return strings.Join(values, ", ")
+}
+
+func mxHosts(r *Report) []string {
+	hosts := []string{}
+	for _, mx := range r.MX {
+		hosts = append(hosts, mx.Host)
+	}
+	return hosts
+}
+
+// transferServers returns the nameservers that answered an AXFR or IXFR.
+func (r *Report) transferServers() []string {
+	seen := map[string]bool{}
+	servers := []string{}
+	for _, check := range r.ZoneTransfer {
+		if (check.AXFR || check.IXFR) && !seen[check.Server] {
+			seen[check.Server] = true
+			servers = append(servers, check.Server)
+		}
+	}
+	for _, check := range r.AXFR {
+		if check.Allowed && !seen[check.Server] {
+			seen[check.Server] = true
+			servers = append(servers, check.Server)
+		}
+	}
+	return servers
+}
@@ -0,0 +1,82 @@
+package pig
+
+import (
+	"net"
+	"testing"
+)
+
+func TestAlerts(t *testing.T) {
+	base := func() *Report {
+		return &Report{
+			Domain:   "example.com",
+			Sections: []string{"ns", "mx", "blocklists", "dnssec", "zonetransfer"},
+			NS:       []NSInfo{{Host: "ns1.example.com."}},
+			MX:       []MXInfo{{Host: "mail.example.com.", Pref: 10}},
+			Addresses: []*AddressInfo{{
+				IP:         net.ParseIP("192.0.2.1"),
+				Blocklists: []*BlocklistResult{{List: "Listed", Zone: "listed.example", Target: "192.0.2.1"}},
+			}},
+			DNSSEC:       &DNSSECStatus{Status: "secure"},
+			ZoneTransfer: []ZoneTransferCheck{{Server: "ns1.example.com."}},
+		}
+	}
+	tests := []struct {
+		name   string
+		change func(r *Report)
+		want   string
+	}{
+		{"ns", func(r *Report) { r.NS = append(r.NS, NSInfo{Host: "ns2.example.net."}) }, "ns nameservers changed from ns1.example.com. to ns1.example.com., ns2.example.net."},
+		{"mx", func(r *Report) { r.MX[0].Host = "mx.example.net." }, "mx mail exchangers changed from mail.example.com. to mx.example.net."},
+		{"spf", func(r *Report) { r.SPF = []string{"v=spf1 -all"} }, "spf SPF changed from none to v=spf1 -all"},
+		{"blocklist", func(r *Report) {
+			r.Addresses[0].Blocklists[0].Listed = true
+			r.Addresses[0].Blocklists[0].Reasons = []string{"spam"}
+		}, "blocklist 192.0.2.1 is listed on Listed (listed.example): spam"},
+		{"dnssec", func(r *Report) { r.DNSSEC = &DNSSECStatus{Status: "bogus", Detail: "expired signature"} }, "dnssec DNSSEC validation is failing: expired signature"},
+		{"zone transfer", func(r *Report) { r.ZoneTransfer[0].AXFR = true }, "zone_transfer ns1.example.com. now allows zone transfers"},
+		{"axfr command", func(r *Report) {
+			r.Sections = append(r.Sections, "axfr")
+			r.AXFR = []AXFRCheck{{Server: "ns2.example.com.", Allowed: true}}
+		}, "zone_transfer ns2.example.com. now allows zone transfers"},
+	}
+	for _, tt := range tests {
+		cur := base()
+		tt.change(cur)
+		alerts := Alerts(base(), cur)
+		got := []string{}
+		for _, a := range alerts {
+			if a.Domain != "example.com" {
+				t.Errorf("%s: alert for %q, want example.com", tt.name, a.Domain)
+			}
+			got = append(got, a.Kind+" "+a.Message)
+		}
+		if !equalStrings(got, []string{tt.want}) {
+			t.Errorf("%s: alerts %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAlertsQuiet(t *testing.T) {
+	old := &Report{Domain: "example.com", Sections: []string{"dnssec"}, DNSSEC: &DNSSECStatus{Status: "bogus"}}
+	cur := &Report{Domain: "example.com", Sections: []string{"dnssec"}, DNSSEC: &DNSSECStatus{Status: "bogus"}}
+	if alerts := Alerts(old, cur); len(alerts) != 0 {
+		t.Errorf("DNSSEC that stayed bogus raised %+v", alerts)
+	}
+
+	// Transfers are only compared when both scans checked them.
+	old = &Report{Domain: "example.com", Sections: []string{"ns"}}
+	cur = &Report{Domain: "example.com", Sections: []string{"zonetransfer"}, ZoneTransfer: []ZoneTransferCheck{{Server: "ns1.example.com.", AXFR: true}}}
+	if alerts := Alerts(old, cur); len(alerts) != 0 {
+		t.Errorf("a transfer check missing from the old scan raised %+v", alerts)
+	}
+
+	// A closed transfer and a delisting are good news.
+	old = &Report{Domain: "example.com", Sections: []string{"zonetransfer", "blocklists"},
+		ZoneTransfer: []ZoneTransferCheck{{Server: "ns1.example.com.", IXFR: true}},
+		Addresses:    []*AddressInfo{{Blocklists: []*BlocklistResult{{Zone: "listed.example", Target: "192.0.2.1", Listed: true}}}}}
+	cur = &Report{Domain: "example.com", Sections: []string{"zonetransfer", "blocklists"},
+		ZoneTransfer: []ZoneTransferCheck{{Server: "ns1.example.com."}}}
+	if alerts := Alerts(old, cur); len(alerts) != 0 {
+		t.Errorf("a closed transfer and a delisting raised %+v", alerts)
+	}
+}
@@ -0,0 +1,150 @@
+package pig
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+type ASNInfo struct {
+	ASN       string   `json:"asn"`
+	Origins   []string `json:"origins,omitempty"`
+	Name      string   `json:"name"`
+	Prefix    string   `json:"prefix"`
+	Country   string   `json:"country"`
+	Registry  string   `json:"registry"`
+	Allocated string   `json:"allocated"`
+	Peers     []string `json:"peers,omitempty"`
+}
+
+func (s *Scanner) cymruFields(name string) ([]string, error) {
+	msg, err := s.query(name, typeTXT)
+	if err != nil {
+		return nil, err
+	}
+	txtRecords := []string{}
+	for _, rr := range msg.answers(name, typeTXT) {
+		txtRecords = append(txtRecords, rr.Data)
+	}
+	if len(txtRecords) < 1 {
+		return nil, fmt.Errorf("no TXT record at %s: %s", name, classify(msg, nil, 0))
+	}
+	fields := strings.Split(txtRecords[0], "|")
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+	return fields, nil
+}
+
+// ASN looks up the origin AS of ip in Team Cymru's IP to ASN service.
+func (s *Scanner) ASN(ip net.IP) (*ASNInfo, error) {
+	s.asn.mu.Lock()
+	cached, ok := s.asn.byIP[ip.String()]
+	s.asn.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	zone, peerZone := "origin.asn.cymru.com", "peer.asn.cymru.com"
+	if ip.To4() == nil {
+		zone, peerZone = "origin6.asn.cymru.com", ""
+	}
+	fields, err := s.cymruFields(fmt.Sprintf("%s.%s", reverseIP(ip.String()), zone))
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) < 5 {
+		return nil, fmt.Errorf("no ASN data for %s", ip)
+	}
+
+	info := &ASNInfo{
+		Origins:   strings.Fields(fields[0]),
+		Prefix:    fields[1],
+		Country:   fields[2],
+		Registry:  fields[3],
+		Allocated: fields[4],
+	}
+	if len(info.Origins) < 1 {
+		return nil, fmt.Errorf("no ASN data for %s", ip)
+	}
+	info.ASN = info.Origins[0]
+	// Results missing a part because a lookup failed are returned but not
+	// cached, so long-running scanners try again next time.
+	var complete bool
+	info.Name, complete = s.asnName(info.ASN)
+	if peerZone != "" {
+		if peers, err := s.cymruFields(fmt.Sprintf("%s.%s", reverseIP(ip.String()), peerZone)); err == nil {
+			info.Peers = strings.Fields(peers[0])
+		} else {
+			complete = false
+		}
+	}
+
+	if complete {
+		s.asn.mu.Lock()
+		s.asn.byIP[ip.String()] = info
+		s.asn.mu.Unlock()
+	}
+	return info, nil
+}
+
+// asnName reads the AS description, e.g. "23028 | US | arin | 2002-01-04 | TEAMCYMRU - SAI, US".
+// It reports false when the lookup failed, and only successful lookups are
+// cached.
+func (s *Scanner) asnName(asn string) (string, bool) {
+	s.asn.mu.Lock()
+	name, ok := s.asn.byName[asn]
+	s.asn.mu.Unlock()
+	if ok {
+		return name, true
+	}
+	fields, err := s.cymruFields(fmt.Sprintf("AS%s.asn.cymru.com", asn))
+	if err != nil || len(fields) < 5 {
+		return "", false
+	}
+	name = fields[4]
+	s.asn.mu.Lock()
+	s.asn.byName[asn] = name
+	s.asn.mu.Unlock()
+	return name, true
+}
+
+// NetworkRoles are the address roles Networks groups by, in report order.
+var NetworkRoles = []string{"web", "mail", "dns"}
+
+type NetworkGroup struct {
+	ASN   string              `json:"asn"`
+	Name  string              `json:"name"`
+	Roles map[string][]string `json:"roles"`
+}
+
+// Networks groups the web, mail and DNS addresses of a domain by origin ASN
+// to show whether they share a single network. The keys of addresses are
+// the roles "web", "mail" and "dns".
+func (s *Scanner) Networks(addresses map[string][]net.IP) []*NetworkGroup {
+	groups := map[string]*NetworkGroup{}
+	for _, role := range NetworkRoles {
+		for _, ip := range addresses[role] {
+			info, err := s.ASN(ip)
+			if err != nil {
+				continue
+			}
+			group, ok := groups[info.ASN]
+			if !ok {
+				group = &NetworkGroup{ASN: info.ASN, Name: info.Name, Roles: map[string][]string{}}
+				groups[info.ASN] = group
+			}
+			group.Roles[role] = append(group.Roles[role], ip.String())
+		}
+	}
+
+	list := make([]*NetworkGroup, 0, len(groups))
+	for _, group := range groups {
+		list = append(list, group)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ASN < list[j].ASN
+	})
+	return list
+}
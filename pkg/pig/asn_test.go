@@ -0,0 +1,42 @@
+package pig
+
+import (
+	"net"
+	"testing"
+)
+
+func TestASNNameRetried(t *testing.T) {
+	srv := newTestServer(t, false,
+		"1.2.0.192.origin.asn.cymru.com 300 TXT 64500 | 192.0.2.0/24 | ZZ | test | 2024-01-01",
+		"1.2.0.192.peer.asn.cymru.com 300 TXT 64501 64502 | 192.0.2.0/24 | ZZ | test | 2024-01-01",
+	)
+	srv.fail("AS64500.asn.cymru.com", "TXT", rcodeServFail)
+	s := newTestScanner(t, srv, Options{})
+	ip := net.ParseIP("192.0.2.1").To4()
+
+	info, err := s.ASN(ip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ASN != "64500" || info.Name != "" {
+		t.Errorf("ASN with a failed name lookup = %+v", info)
+	}
+
+	// The failure was not cached, so the name is found once the lookup
+	// works again, and then it is.
+	srv.mu.Lock()
+	delete(srv.rcodes, "as64500.asn.cymru.com. TXT")
+	srv.mu.Unlock()
+	srv.add(t, "AS64500.asn.cymru.com 300 TXT 64500 | ZZ | test | 2024-01-01 | EXAMPLE-NET, ZZ")
+	for i := 0; i < 2; i++ {
+		if info, err = s.ASN(ip); err != nil || info.Name != "EXAMPLE-NET, ZZ" {
+			t.Errorf("ASN after the lookup recovered = %+v, %v", info, err)
+		}
+	}
+	if !equalStrings(info.Peers, []string{"64501", "64502"}) {
+		t.Errorf("peers %v", info.Peers)
+	}
+	if n := srv.count(); n != 6 {
+		t.Errorf("sent %d queries, want 6: three per uncached lookup", n)
+	}
+}
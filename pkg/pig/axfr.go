@@ -0,0 +1,181 @@
+package pig
+
+import (
+	"net"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+type RateLimitCheck struct {
+	Attempt int    `json:"attempt,omitempty"`
+	Elapsed string `json:"elapsed,omitempty"`
+}
+
+type ZoneTransferCheck struct {
+	Server      string         `json:"server"`
+	AXFR        bool           `json:"axfr"`
+	AXFRRecords int            `json:"axfr_records,omitempty"`
+	IXFR        bool           `json:"ixfr"`
+	IXFRRecords int            `json:"ixfr_records,omitempty"`
+	TCP         bool           `json:"tcp"`
+	DNSSEC      bool           `json:"dnssec"`
+	RateLimit   RateLimitCheck `json:"rate_limit"`
+}
+
+type AmplificationCheck struct {
+	Type         string  `json:"type"`
+	QuerySize    int     `json:"query_size"`
+	ResponseSize int     `json:"response_size"`
+	Factor       float64 `json:"factor"`
+}
+
+type AXFRCheck struct {
+	Server    string         `json:"server"`
+	Error     string         `json:"error,omitempty"`
+	Refused   bool           `json:"refused,omitempty"`
+	Allowed   bool           `json:"allowed"`
+	Records   int            `json:"records,omitempty"`
+	Types     map[string]int `json:"types,omitempty"`
+	IXFR      bool           `json:"ixfr,omitempty"`
+	RateLimit RateLimitCheck `json:"rate_limit"`
+}
+
+// checkRateLimit repeats a dig AXFR query against ns and records the first
+// attempt that takes longer than two seconds.
+func checkRateLimit(probes *probeLog, ns string, attempts int, args ...string) RateLimitCheck {
+	for i := 0; i < attempts; i++ {
+		probes.add("axfr", ns)
+		start := time.Now()
+		exec.Command("dig", args...).Run()
+		elapsed := time.Since(start)
+		if elapsed > time.Second*2 {
+			return RateLimitCheck{Attempt: i + 1, Elapsed: elapsed.String()}
+		}
+		time.Sleep(time.Millisecond * 100)
+	}
+	return RateLimitCheck{}
+}
+
+// ZoneTransfer tries AXFR and IXFR against each nameserver and checks
+// whether it answers over TCP and serves DNSKEY records. It returns nil
+// unless the scanner's profile is Active.
+func (s *Scanner) ZoneTransfer(domain string, nameservers []string) []ZoneTransferCheck {
+	if s.profile != Active {
+		return nil
+	}
+	return s.zoneTransfer(domain, nameservers, nil)
+}
+
+func (s *Scanner) zoneTransfer(domain string, nameservers []string, probes *probeLog) []ZoneTransferCheck {
+	checks := []ZoneTransferCheck{}
+	for _, ns := range nameservers {
+		check := ZoneTransferCheck{Server: ns}
+
+		probes.add("axfr", ns)
+		probes.add("ixfr", ns)
+		axfrOutput, _ := exec.Command("dig", "+short", "+time=5", "+tries=1", "axfr", domain, "@"+ns).CombinedOutput()
+		ixfrOutput, _ := exec.Command("dig", "+short", "+time=5", "+tries=1", "ixfr=1", domain, "@"+ns).CombinedOutput()
+		check.AXFR = !strings.Contains(string(axfrOutput), "Transfer failed") && len(axfrOutput) > 0
+		check.IXFR = !strings.Contains(string(ixfrOutput), "Transfer failed") && len(ixfrOutput) > 0
+		if check.AXFR {
+			check.AXFRRecords = strings.Count(string(axfrOutput), "\n")
+		}
+		if check.IXFR {
+			check.IXFRRecords = strings.Count(string(ixfrOutput), "\n")
+		}
+
+		probes.add("tcp", ns)
+		if tcpConn, err := net.DialTimeout("tcp", net.JoinHostPort(strings.TrimSuffix(ns, "."), "53"), time.Second*5); err == nil {
+			tcpConn.Close()
+			check.TCP = true
+		}
+
+		probes.add("dnskey", ns)
+		dnssecOutput, _ := exec.Command("dig", "+short", "+dnssec", domain, "DNSKEY", "@"+ns).CombinedOutput()
+		check.DNSSEC = len(dnssecOutput) > 0
+
+		check.RateLimit = checkRateLimit(probes, ns, 3, "+short", "+time=2", "+tries=1", "axfr", domain, "@"+ns)
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// Amplification compares query and response sizes for the record types
+// most often abused in reflection attacks. The queries go to the resolver
+// over its transport. It returns nil unless the scanner's profile is Active.
+func (s *Scanner) Amplification(domain string) []AmplificationCheck {
+	if s.profile != Active {
+		return nil
+	}
+	return s.amplification(domain, nil)
+}
+
+func (s *Scanner) amplification(domain string, probes *probeLog) []AmplificationCheck {
+	checks := []AmplificationCheck{}
+	for _, qtype := range []uint16{typeANY, typeTXT, typeRRSIG, typeDNSKEY} {
+		probes.add("amplification", typeString(qtype)+" "+domain)
+		query, _, err := packQuery(domain, qtype, 0)
+		if err != nil {
+			return checks
+		}
+		msg, err := s.query(domain, qtype)
+		if err != nil {
+			continue
+		}
+		checks = append(checks, AmplificationCheck{
+			Type:         typeString(qtype),
+			QuerySize:    len(query),
+			ResponseSize: msg.Size,
+			Factor:       float64(msg.Size) / float64(len(query)),
+		})
+	}
+	return checks
+}
+
+// AXFR requests a full zone transfer from each nameserver and counts the
+// record types it returns. It returns nil unless the scanner's profile is
+// Active.
+func (s *Scanner) AXFR(domain string, nameservers []string) []AXFRCheck {
+	if s.profile != Active {
+		return nil
+	}
+	return s.axfr(domain, nameservers, nil)
+}
+
+func (s *Scanner) axfr(domain string, nameservers []string, probes *probeLog) []AXFRCheck {
+	checks := []AXFRCheck{}
+	for _, ns := range nameservers {
+		check := AXFRCheck{Server: ns}
+		probes.add("axfr", ns)
+		output, err := exec.Command("dig", "+short", "axfr", domain, "@"+ns).CombinedOutput()
+		if err != nil {
+			check.Error = err.Error()
+			checks = append(checks, check)
+			continue
+		}
+
+		outputStr := string(output)
+		if strings.Contains(outputStr, "Transfer failed.") || strings.Contains(outputStr, "connection refused") {
+			check.Refused = true
+		} else if len(outputStr) > 0 {
+			check.Allowed = true
+			records := strings.Split(outputStr, "\n")
+			check.Records = len(records)
+			check.Types = make(map[string]int)
+			for _, record := range records {
+				fields := strings.Fields(record)
+				if len(fields) >= 4 {
+					check.Types[fields[3]]++
+				}
+			}
+			probes.add("ixfr", ns)
+			ixfrOutput, _ := exec.Command("dig", "+short", "ixfr=1", domain, "@"+ns).CombinedOutput()
+			check.IXFR = !strings.Contains(string(ixfrOutput), "Transfer failed.")
+		}
+
+		check.RateLimit = checkRateLimit(probes, ns, 5, "+short", "axfr", domain, "@"+ns)
+		checks = append(checks, check)
+	}
+	return checks
+}
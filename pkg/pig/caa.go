@@ -0,0 +1,70 @@
+package pig
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// caaProblems returns the syntax errors in the presentation form of a CAA
+// record, flags tag "value" (RFC 8659).
+func caaProblems(data string) []string {
+	fields := strings.SplitN(data, " ", 3)
+	if len(fields) < 3 {
+		return []string{"record needs flags, a tag and a value"}
+	}
+	problems := []string{}
+	flags, err := strconv.Atoi(fields[0])
+	if err != nil || flags < 0 || flags > 255 {
+		problems = append(problems, fmt.Sprintf("flags %s must be a number from 0 to 255", fields[0]))
+	} else if flags&^128 != 0 {
+		problems = append(problems, fmt.Sprintf("flags %d set reserved bits, only 128 (critical) is defined", flags))
+	}
+	tag := fields[1]
+	value, err := strconv.Unquote(fields[2])
+	if err != nil {
+		value = fields[2]
+	}
+
+	switch strings.ToLower(tag) {
+	case "issue", "issuewild":
+		issuer, _, _ := strings.Cut(value, ";")
+		issuer = strings.TrimSpace(issuer)
+		if issuer != "" && !validHostname(issuer) {
+			problems = append(problems, fmt.Sprintf("%s value %q is not a CA domain name", tag, issuer))
+		}
+	case "iodef":
+		lower := strings.ToLower(value)
+		if !strings.HasPrefix(lower, "mailto:") && !strings.HasPrefix(lower, "https://") && !strings.HasPrefix(lower, "http://") {
+			problems = append(problems, fmt.Sprintf("iodef value %q must be a mailto:, http: or https: URL", value))
+		}
+	case "issuemail", "issuevmc", "contactemail", "contactphone":
+	default:
+		if flags&128 != 0 {
+			problems = append(problems, fmt.Sprintf("unknown tag %s is marked critical, so CAs will refuse to issue", tag))
+		} else {
+			problems = append(problems, fmt.Sprintf("unknown tag %s", tag))
+		}
+	}
+	return problems
+}
+
+// validHostname reports whether name is made of letters, digits and
+// hyphens in dot-separated labels.
+func validHostname(name string) bool {
+	name = strings.TrimSuffix(name, ".")
+	if name == "" || len(name) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
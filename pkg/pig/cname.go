@@ -0,0 +1,153 @@
+package pig
+
+import (
+	"net"
+	"strings"
+)
+
+// MaxCNAMEChain is the number of hops CNAMEChain follows before giving up.
+const MaxCNAMEChain = 8
+
+type CNAMEHop struct {
+	Name      string   `json:"name"`
+	Target    string   `json:"target"`
+	TTL       uint32   `json:"ttl"`
+	Apex      bool     `json:"apex,omitempty"`
+	Conflicts []string `json:"conflicts,omitempty"`
+}
+
+type CNAMEChain struct {
+	Name    string     `json:"name"`
+	Hops    []CNAMEHop `json:"hops"`
+	Self    bool       `json:"self,omitempty"`
+	Loop    bool       `json:"loop,omitempty"`
+	TooLong bool       `json:"too_long,omitempty"`
+}
+
+func (c *CNAMEChain) Target() string {
+	if len(c.Hops) == 0 {
+		return c.Name
+	}
+	return c.Hops[len(c.Hops)-1].Target
+}
+
+// CNAMEChain follows the CNAMEs of domain hop by hop. Outside the Passive
+// profile, each name is also checked at a nameserver of its zone for data
+// that must not sit next to a CNAME.
+func (s *Scanner) CNAMEChain(domain string) (*CNAMEChain, error) {
+	return s.cnameChain(domain, nil)
+}
+
+func (s *Scanner) cnameChain(domain string, probes *probeLog) (*CNAMEChain, error) {
+	chain := &CNAMEChain{Name: fqdn(domain)}
+	seen := map[string]bool{chain.Name: true}
+	servers := map[string]string{}
+	name := chain.Name
+	for {
+		msg, err := s.query(name, typeCNAME)
+		if err != nil {
+			return chain, err
+		}
+		cnames := msg.answers(name, typeCNAME)
+		if len(cnames) == 0 {
+			return chain, nil
+		}
+
+		hop := CNAMEHop{Name: name, Target: cnames[0].Data, TTL: cnames[0].TTL}
+		if s.profile != Passive {
+			hop.Apex, hop.Conflicts = s.cnameConflicts(name, servers, probes)
+		}
+		chain.Hops = append(chain.Hops, hop)
+
+		switch {
+		case hop.Target == name:
+			chain.Self = true
+			return chain, nil
+		case seen[hop.Target]:
+			chain.Loop = true
+			return chain, nil
+		case len(chain.Hops) >= MaxCNAMEChain:
+			chain.TooLong = true
+			return chain, nil
+		}
+		seen[hop.Target] = true
+		name = hop.Target
+	}
+}
+
+// cnameConflicts looks for data that must not coexist with a CNAME at name:
+// the NS records of a zone apex or delegation, or any other record set. A
+// resolver would follow the CNAME, so the questions go to a nameserver of
+// the zone holding name. servers caches the nameserver of each zone.
+func (s *Scanner) cnameConflicts(name string, servers map[string]string, probes *probeLog) (bool, []string) {
+	zone := s.zoneOf(name)
+	if zone == "" {
+		return false, nil
+	}
+	server, ok := servers[zone]
+	if !ok {
+		server = s.zoneServer(zone)
+		servers[zone] = server
+	}
+	if server == "" {
+		return false, nil
+	}
+
+	apex := zone == name
+	conflicts := []string{}
+	for _, t := range []uint16{typeNS, typeMX, typeTXT, typeA, typeAAAA} {
+		probes.add("query", server)
+		msg, err := s.exchangeWith(server, name, t, 0)
+		if err != nil {
+			continue
+		}
+		if t != typeNS {
+			if len(msg.answers(name, t)) > 0 {
+				conflicts = append(conflicts, typeString(t))
+			}
+			continue
+		}
+		// NS records at name are the apex of a zone, or a delegation
+		// that the parent's nameserver answers with a referral.
+		if len(msg.answers(name, typeNS)) > 0 {
+			apex = true
+		}
+		for _, rr := range msg.Authority {
+			if rr.Type == typeNS && rr.Name == name {
+				apex = true
+			}
+		}
+	}
+	return apex, conflicts
+}
+
+// zoneOf returns the closest enclosing zone of name that has an SOA record,
+// or "" when none is found.
+func (s *Scanner) zoneOf(name string) string {
+	for zone := fqdn(name); zone != "."; {
+		if msg, err := s.query(zone, typeSOA); err == nil && len(msg.answers(zone, typeSOA)) > 0 {
+			return zone
+		}
+		_, parent, ok := strings.Cut(zone, ".")
+		if !ok || parent == "" {
+			break
+		}
+		zone = parent
+	}
+	return ""
+}
+
+// zoneServer returns the address of the first nameserver of zone that has
+// one, or "".
+func (s *Scanner) zoneServer(zone string) string {
+	msg, err := s.query(zone, typeNS)
+	if err != nil {
+		return ""
+	}
+	for _, rr := range msg.answers(zone, typeNS) {
+		for _, ip := range s.lookupIP(rr.Data) {
+			return net.JoinHostPort(ip.String(), nameserverPort)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,113 @@
+package pig
+
+import (
+	"net"
+	"testing"
+)
+
+// useNameserver makes queries to nameservers go to the port of srv, whose
+// address the resolver gives for every nameserver host.
+func useNameserver(t *testing.T, srv *testServer) {
+	_, port, _ := net.SplitHostPort(srv.addr)
+	saved := nameserverPort
+	nameserverPort = port
+	t.Cleanup(func() { nameserverPort = saved })
+}
+
+func TestCNAMEChainConflicts(t *testing.T) {
+	resolver := newTestServer(t, false,
+		"example.com 300 SOA ns1.example.com hostmaster.example.com 1 3600 600 86400 300",
+		"example.com 300 NS ns1.example.com",
+		"ns1.example.com 300 A 127.0.0.1",
+		"www.example.com 300 CNAME cdn.example.net",
+		"shop.example.com 300 CNAME shops.example.org",
+		"cdn.example.net 300 A 192.0.2.1",
+		"shops.example.org 300 A 192.0.2.2",
+	)
+	auth := newTestServer(t, true,
+		"example.com 300 SOA ns1.example.com hostmaster.example.com 1 3600 600 86400 300",
+		"example.com 300 NS ns1.example.com",
+		"www.example.com 300 CNAME cdn.example.net",
+		"www.example.com 300 TXT google-site-verification=abc",
+		"www.example.com 300 MX 10 mail.example.com",
+		"shop.example.com 300 CNAME shops.example.org",
+	)
+	useNameserver(t, auth)
+	s := newTestScanner(t, resolver, Options{})
+
+	tests := []struct {
+		domain    string
+		target    string
+		apex      bool
+		conflicts []string
+	}{
+		{"www.example.com", "cdn.example.net.", false, []string{"MX", "TXT"}},
+		{"shop.example.com", "shops.example.org.", false, []string{}},
+	}
+	for _, tt := range tests {
+		chain, err := s.CNAMEChain(tt.domain)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.domain, err)
+		}
+		if len(chain.Hops) != 1 || chain.Target() != tt.target {
+			t.Fatalf("%s: chain %+v, want one hop to %s", tt.domain, chain, tt.target)
+		}
+		hop := chain.Hops[0]
+		if hop.Apex != tt.apex || !equalStrings(hop.Conflicts, tt.conflicts) {
+			t.Errorf("%s: apex %v, conflicts %v, want %v, %v", tt.domain, hop.Apex, hop.Conflicts, tt.apex, tt.conflicts)
+		}
+	}
+}
+
+func TestCNAMEChainApex(t *testing.T) {
+	records := []string{
+		"example.com 300 SOA ns1.example.com hostmaster.example.com 1 3600 600 86400 300",
+		"example.com 300 NS ns1.example.com",
+		"example.com 300 CNAME site.example.net",
+		"ns1.example.com 300 A 127.0.0.1",
+		"site.example.net 300 A 192.0.2.1",
+	}
+	resolver := newTestServer(t, false, records...)
+	auth := newTestServer(t, true, records...)
+	useNameserver(t, auth)
+
+	chain, err := newTestScanner(t, resolver, Options{}).CNAMEChain("example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chain.Hops) != 1 || !chain.Hops[0].Apex {
+		t.Errorf("CNAME at the apex: got %+v", chain.Hops)
+	}
+}
+
+func TestCNAMEChainPassive(t *testing.T) {
+	resolver := newTestServer(t, false,
+		"a.example.com 300 CNAME b.example.com",
+		"b.example.com 300 CNAME a.example.com",
+	)
+	auth := newTestServer(t, true)
+	useNameserver(t, auth)
+
+	chain, err := newTestScanner(t, resolver, Options{Profile: Passive}).CNAMEChain("a.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !chain.Loop || len(chain.Hops) != 2 {
+		t.Errorf("chain %+v, want a loop of two hops", chain)
+	}
+	if auth.count() != 0 {
+		t.Errorf("passive profile sent %d queries to the nameserver", auth.count())
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
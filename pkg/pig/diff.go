@@ -0,0 +1,182 @@
+package pig
+
+import (
+	"sort"
+	"time"
+)
+
+type RecordChange struct {
+	Old Record `json:"old"`
+	New Record `json:"new"`
+}
+
+type ReportDiff struct {
+	Domain    string             `json:"domain"`
+	Old       time.Time          `json:"old"`
+	New       time.Time          `json:"new"`
+	Added     []Record           `json:"added,omitempty"`
+	Removed   []Record           `json:"removed,omitempty"`
+	Changed   []RecordChange     `json:"changed,omitempty"`
+	NSAdded   []string           `json:"ns_added,omitempty"`
+	NSRemoved []string           `json:"ns_removed,omitempty"`
+	OldSPF    []string           `json:"old_spf,omitempty"`
+	NewSPF    []string           `json:"new_spf,omitempty"`
+	Listed    []*BlocklistResult `json:"listed,omitempty"`
+	Delisted  []*BlocklistResult `json:"delisted,omitempty"`
+}
+
+func (d *ReportDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0 &&
+		len(d.NSAdded) == 0 && len(d.NSRemoved) == 0 && len(d.OldSPF) == 0 && len(d.NewSPF) == 0 &&
+		len(d.Listed) == 0 && len(d.Delisted) == 0
+}
+
+// singleValued record types are reported as changed rather than removed and
+// added when their data differs.
+var singleValued = map[string]bool{"CNAME": true, "SOA": true}
+
+// Diff compares two reports of the same domain. TTLs are not compared,
+// since the resolver counts them down between scans. Blocklist listings are
+// only compared when both reports checked them.
+func Diff(old, cur *Report) *ReportDiff {
+	d := &ReportDiff{Domain: cur.Domain, Old: old.Time, New: cur.Time}
+
+	key := func(rec Record) string {
+		return rec.Name + " " + rec.Type + " " + rec.Data
+	}
+	oldRecords := map[string]Record{}
+	for _, rec := range old.Records {
+		oldRecords[key(rec)] = rec
+	}
+	newRecords := map[string]Record{}
+	for _, rec := range cur.Records {
+		newRecords[key(rec)] = rec
+	}
+	for k, rec := range newRecords {
+		if _, ok := oldRecords[k]; !ok {
+			d.Added = append(d.Added, rec)
+		}
+	}
+	for k, rec := range oldRecords {
+		if _, ok := newRecords[k]; !ok {
+			d.Removed = append(d.Removed, rec)
+		}
+	}
+	d.pairSingleValued()
+	sortRecords(d.Added)
+	sortRecords(d.Removed)
+	sort.Slice(d.Changed, func(i, j int) bool {
+		return key(d.Changed[i].New) < key(d.Changed[j].New)
+	})
+
+	d.NSAdded, d.NSRemoved = diffStrings(old.Nameservers(), cur.Nameservers())
+	if added, removed := diffStrings(old.SPF, cur.SPF); len(added) > 0 || len(removed) > 0 {
+		d.OldSPF, d.NewSPF = old.SPF, cur.SPF
+	}
+
+	if !old.Has("blocklists") || !cur.Has("blocklists") {
+		return d
+	}
+	// A lookup that failed in the new report says nothing about whether
+	// the listing is still there.
+	oldResults, newResults := old.blocklistResults(), cur.blocklistResults()
+	for k, res := range newResults {
+		if prev := oldResults[k]; res.Listed && (prev == nil || !prev.Listed) {
+			d.Listed = append(d.Listed, res)
+		}
+	}
+	for k, res := range oldResults {
+		if next := newResults[k]; res.Listed && (next == nil || !next.Listed && next.Error == "") {
+			d.Delisted = append(d.Delisted, res)
+		}
+	}
+	sortListings(d.Listed)
+	sortListings(d.Delisted)
+	return d
+}
+
+func (d *ReportDiff) pairSingleValued() {
+	added := d.Added[:0]
+	for _, rec := range d.Added {
+		paired := false
+		if singleValued[rec.Type] {
+			for i, prev := range d.Removed {
+				if prev.Name == rec.Name && prev.Type == rec.Type {
+					d.Changed = append(d.Changed, RecordChange{Old: prev, New: rec})
+					d.Removed = append(d.Removed[:i], d.Removed[i+1:]...)
+					paired = true
+					break
+				}
+			}
+		}
+		if !paired {
+			added = append(added, rec)
+		}
+	}
+	d.Added = added
+}
+
+func sortRecords(records []Record) {
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Name != records[j].Name {
+			return records[i].Name < records[j].Name
+		}
+		if records[i].Type != records[j].Type {
+			return records[i].Type < records[j].Type
+		}
+		return records[i].Data < records[j].Data
+	})
+}
+
+func sortListings(results []*BlocklistResult) {
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Target != results[j].Target {
+			return results[i].Target < results[j].Target
+		}
+		return results[i].Zone < results[j].Zone
+	})
+}
+
+func diffStrings(old, cur []string) (added, removed []string) {
+	seen := map[string]bool{}
+	for _, s := range old {
+		seen[s] = true
+	}
+	for _, s := range cur {
+		if !seen[s] {
+			added = append(added, s)
+		}
+		delete(seen, s)
+	}
+	for _, s := range old {
+		if seen[s] {
+			removed = append(removed, s)
+		}
+	}
+	return added, removed
+}
+
+// blocklistResults returns every address, mail server and domain blocklist
+// result of the report keyed by target and zone.
+func (r *Report) blocklistResults() map[string]*BlocklistResult {
+	results := map[string]*BlocklistResult{}
+	add := func(res *BlocklistResult) {
+		results[res.Target+" "+res.Zone] = res
+	}
+	for _, addr := range r.Addresses {
+		for _, res := range addr.Blocklists {
+			add(res)
+		}
+	}
+	for _, mx := range r.MX {
+		for _, res := range mx.Blocklists {
+			add(res)
+		}
+	}
+	if r.DomainBlocklists != nil {
+		for _, res := range r.DomainBlocklists.Results {
+			add(res)
+		}
+	}
+	return results
+}
@@ -0,0 +1,106 @@
+package pig
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// dmarcTags splits a DMARC record into its tags, in order.
+func dmarcTags(record string) [][2]string {
+	tags := [][2]string{}
+	for _, part := range strings.Split(record, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(part, "=")
+		tags = append(tags, [2]string{strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(value)})
+	}
+	return tags
+}
+
+// dmarcTag returns the value of a tag in a DMARC record.
+func dmarcTag(record, tag string) string {
+	for _, t := range dmarcTags(record) {
+		if t[0] == tag {
+			return strings.ToLower(t[1])
+		}
+	}
+	return ""
+}
+
+// dmarcProblems returns the syntax errors in a DMARC record (RFC 7489
+// section 6.3).
+func dmarcProblems(record string) []string {
+	problems := []string{}
+	tags := dmarcTags(record)
+	if len(tags) < 1 || tags[0][0] != "v" || tags[0][1] != "DMARC1" {
+		problems = append(problems, "record does not start with v=DMARC1")
+	}
+	seen := map[string]bool{}
+	for i, t := range tags {
+		name, value := t[0], t[1]
+		if seen[name] {
+			problems = append(problems, fmt.Sprintf("tag %s appears more than once", name))
+		}
+		seen[name] = true
+		switch name {
+		case "v":
+			if i > 0 {
+				problems = append(problems, "v must be the first tag")
+			}
+		case "p", "sp":
+			if !oneOf(strings.ToLower(value), "none", "quarantine", "reject") {
+				problems = append(problems, fmt.Sprintf("%s=%s must be none, quarantine or reject", name, value))
+			}
+			if name == "p" && i != 1 {
+				problems = append(problems, "p must directly follow v")
+			}
+		case "adkim", "aspf":
+			if !oneOf(strings.ToLower(value), "r", "s") {
+				problems = append(problems, fmt.Sprintf("%s=%s must be r or s", name, value))
+			}
+		case "pct":
+			if n, err := strconv.Atoi(value); err != nil || n < 0 || n > 100 {
+				problems = append(problems, fmt.Sprintf("pct=%s must be a number from 0 to 100", value))
+			}
+		case "ri":
+			if _, err := strconv.ParseUint(value, 10, 32); err != nil {
+				problems = append(problems, fmt.Sprintf("ri=%s must be a number of seconds", value))
+			}
+		case "fo":
+			for _, opt := range strings.Split(value, ":") {
+				if !oneOf(strings.ToLower(strings.TrimSpace(opt)), "0", "1", "d", "s") {
+					problems = append(problems, fmt.Sprintf("fo option %q must be 0, 1, d or s", opt))
+				}
+			}
+		case "rf":
+			if !strings.EqualFold(value, "afrf") {
+				problems = append(problems, fmt.Sprintf("rf=%s must be afrf", value))
+			}
+		case "rua", "ruf":
+			for _, uri := range strings.Split(value, ",") {
+				uri = strings.TrimSpace(uri)
+				if !strings.HasPrefix(strings.ToLower(uri), "mailto:") || !strings.Contains(uri, "@") {
+					problems = append(problems, fmt.Sprintf("%s address %q is not a mailto: URI", name, uri))
+				}
+			}
+		default:
+			problems = append(problems, fmt.Sprintf("unknown tag %s", name))
+		}
+	}
+	if !seen["p"] {
+		problems = append(problems, "required tag p is missing")
+	}
+	return problems
+}
+
+func oneOf(value string, options ...string) bool {
+	for _, option := range options {
+		if value == option {
+			return true
+		}
+	}
+	return false
+}
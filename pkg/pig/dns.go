@@ -0,0 +1,463 @@
+package pig
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	typeA      uint16 = 1
+	typeNS     uint16 = 2
+	typeCNAME  uint16 = 5
+	typeSOA    uint16 = 6
+	typePTR    uint16 = 12
+	typeMX     uint16 = 15
+	typeTXT    uint16 = 16
+	typeAAAA   uint16 = 28
+	typeSRV    uint16 = 33
+	typeOPT    uint16 = 41
+	typeDS     uint16 = 43
+	typeRRSIG  uint16 = 46
+	typeDNSKEY uint16 = 48
+	typeSVCB   uint16 = 64
+	typeHTTPS  uint16 = 65
+	typeANY    uint16 = 255
+	typeCAA    uint16 = 257
+
+	classINET uint16 = 1
+
+	rcodeSuccess  = 0
+	rcodeFormErr  = 1
+	rcodeServFail = 2
+	rcodeNXDomain = 3
+	rcodeNotImp   = 4
+	rcodeRefused  = 5
+)
+
+// nameserverPort is the port pig sends queries to a domain's nameservers on.
+var nameserverPort = "53"
+
+var typeNames = map[uint16]string{
+	typeA:      "A",
+	typeNS:     "NS",
+	typeCNAME:  "CNAME",
+	typeSOA:    "SOA",
+	typePTR:    "PTR",
+	typeMX:     "MX",
+	typeTXT:    "TXT",
+	typeAAAA:   "AAAA",
+	typeSRV:    "SRV",
+	typeOPT:    "OPT",
+	typeDS:     "DS",
+	typeRRSIG:  "RRSIG",
+	typeDNSKEY: "DNSKEY",
+	typeSVCB:   "SVCB",
+	typeHTTPS:  "HTTPS",
+	typeANY:    "ANY",
+	typeCAA:    "CAA",
+}
+
+var rcodeNames = map[int]string{
+	rcodeSuccess:  "NOERROR",
+	rcodeFormErr:  "FORMERR",
+	rcodeServFail: "SERVFAIL",
+	rcodeNXDomain: "NXDOMAIN",
+	rcodeNotImp:   "NOTIMP",
+	rcodeRefused:  "REFUSED",
+}
+
+// Query flags for dnsExchange.
+const (
+	queryDO uint16 = 1 << iota // ask for DNSSEC records
+	queryCD                    // checking disabled, the resolver skips validation
+)
+
+var errMalformed = errors.New("malformed DNS message")
+
+func typeString(t uint16) string {
+	if name, ok := typeNames[t]; ok {
+		return name
+	}
+	return "TYPE" + strconv.Itoa(int(t))
+}
+
+func rcodeString(rcode int) string {
+	if name, ok := rcodeNames[rcode]; ok {
+		return name
+	}
+	return "RCODE" + strconv.Itoa(rcode)
+}
+
+type dnsRR struct {
+	Name  string
+	Type  uint16
+	Class uint16
+	TTL   uint32
+	Data  string
+	Raw   []byte
+}
+
+type dnsMsg struct {
+	ID                 uint16
+	Rcode              int
+	Authoritative      bool
+	Truncated          bool
+	RecursionAvailable bool
+	AuthenticData      bool
+	Answer             []dnsRR
+	Authority          []dnsRR
+	Additional         []dnsRR
+	// Size is the length of the message on the wire.
+	Size int
+}
+
+// answers returns the answer records of type t owned by name.
+func (m *dnsMsg) answers(name string, t uint16) []dnsRR {
+	rrs := []dnsRR{}
+	for _, rr := range m.Answer {
+		if rr.Type == t && strings.EqualFold(rr.Name, fqdn(name)) {
+			rrs = append(rrs, rr)
+		}
+	}
+	return rrs
+}
+
+func fqdn(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return strings.ToLower(name)
+	}
+	return strings.ToLower(name) + "."
+}
+
+// SystemResolver returns the first nameserver in /etc/resolv.conf as
+// host:port, falling back to 8.8.8.8:53.
+func SystemResolver() string {
+	f, err := os.Open("/etc/resolv.conf")
+	if err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) >= 2 && fields[0] == "nameserver" {
+				return net.JoinHostPort(fields[1], "53")
+			}
+		}
+	}
+	return "8.8.8.8:53"
+}
+
+func dnsExchange(server, name string, qtype uint16, flags uint16, timeout time.Duration) (*dnsMsg, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	query, id, err := packQuery(name, qtype, flags)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.DialTimeout("udp", server, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 65535)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		msg, err := unpackMsg(buf[:n])
+		if err != nil || msg.ID != id {
+			continue
+		}
+		if msg.Truncated {
+			return dnsExchangeTCP(server, query, id, timeout)
+		}
+		return msg, nil
+	}
+}
+
+func dnsExchangeTCP(server string, query []byte, id uint16, timeout time.Duration) (*dnsMsg, error) {
+	conn, err := net.DialTimeout("tcp", server, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return streamExchange(conn, query, id, timeout)
+}
+
+// streamExchange sends a query over a TCP or TLS connection, each message
+// prefixed with its length.
+func streamExchange(conn net.Conn, query []byte, id uint16, timeout time.Duration) (*dnsMsg, error) {
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	framed := make([]byte, 2+len(query))
+	binary.BigEndian.PutUint16(framed, uint16(len(query)))
+	copy(framed[2:], query)
+	if _, err := conn.Write(framed); err != nil {
+		return nil, err
+	}
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return nil, err
+	}
+	msg, err := unpackMsg(buf)
+	if err != nil {
+		return nil, err
+	}
+	if msg.ID != id {
+		return nil, errMalformed
+	}
+	return msg, nil
+}
+
+func packQuery(name string, qtype uint16, flags uint16) ([]byte, uint16, error) {
+	var idBytes [2]byte
+	rand.Read(idBytes[:])
+	id := binary.BigEndian.Uint16(idBytes[:])
+
+	msg := make([]byte, 12, 512)
+	binary.BigEndian.PutUint16(msg[0:], id)
+	header := uint16(0x0100)
+	if flags&queryCD != 0 {
+		header |= 0x0010
+	}
+	binary.BigEndian.PutUint16(msg[2:], header)
+	binary.BigEndian.PutUint16(msg[4:], 1)
+	binary.BigEndian.PutUint16(msg[10:], 1)
+
+	msg, err := packName(msg, name)
+	if err != nil {
+		return nil, 0, err
+	}
+	msg = binary.BigEndian.AppendUint16(msg, qtype)
+	msg = binary.BigEndian.AppendUint16(msg, classINET)
+
+	// EDNS0 OPT record advertising a 4096 byte payload, with the DO bit when asked.
+	var ednsFlags uint16
+	if flags&queryDO != 0 {
+		ednsFlags = 0x8000
+	}
+	msg = append(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, typeOPT)
+	msg = binary.BigEndian.AppendUint16(msg, 4096)
+	msg = append(msg, 0, 0)
+	msg = binary.BigEndian.AppendUint16(msg, ednsFlags)
+	msg = binary.BigEndian.AppendUint16(msg, 0)
+	return msg, id, nil
+}
+
+func packName(msg []byte, name string) ([]byte, error) {
+	name = strings.TrimSuffix(name, ".")
+	if name == "" {
+		return append(msg, 0), nil
+	}
+	for _, label := range strings.Split(name, ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return nil, fmt.Errorf("invalid domain name %q", name)
+		}
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	return append(msg, 0), nil
+}
+
+func unpackMsg(msg []byte) (*dnsMsg, error) {
+	if len(msg) < 12 {
+		return nil, errMalformed
+	}
+	flags := binary.BigEndian.Uint16(msg[2:])
+	m := &dnsMsg{
+		ID:                 binary.BigEndian.Uint16(msg[0:]),
+		Rcode:              int(flags & 0x000f),
+		Authoritative:      flags&0x0400 != 0,
+		Truncated:          flags&0x0200 != 0,
+		RecursionAvailable: flags&0x0080 != 0,
+		AuthenticData:      flags&0x0020 != 0,
+		Size:               len(msg),
+	}
+	qdcount := int(binary.BigEndian.Uint16(msg[4:]))
+	counts := []int{
+		int(binary.BigEndian.Uint16(msg[6:])),
+		int(binary.BigEndian.Uint16(msg[8:])),
+		int(binary.BigEndian.Uint16(msg[10:])),
+	}
+
+	off := 12
+	for i := 0; i < qdcount; i++ {
+		_, next, err := unpackName(msg, off)
+		if err != nil {
+			return nil, err
+		}
+		off = next + 4
+	}
+
+	sections := []*[]dnsRR{&m.Answer, &m.Authority, &m.Additional}
+	for i, count := range counts {
+		for j := 0; j < count; j++ {
+			rr, next, err := unpackRR(msg, off)
+			if err != nil {
+				return nil, err
+			}
+			off = next
+			if rr.Type == typeOPT {
+				// The extended RCODE lives in the upper byte of the OPT TTL.
+				m.Rcode |= int(rr.TTL>>24) << 4
+				continue
+			}
+			*sections[i] = append(*sections[i], rr)
+		}
+	}
+	return m, nil
+}
+
+func unpackName(msg []byte, off int) (string, int, error) {
+	labels := []string{}
+	next := -1
+	for hops := 0; ; hops++ {
+		if off >= len(msg) || hops > 127 {
+			return "", 0, errMalformed
+		}
+		length := int(msg[off])
+		switch {
+		case length == 0:
+			if next < 0 {
+				next = off + 1
+			}
+			return strings.Join(labels, ".") + ".", next, nil
+		case length&0xc0 == 0xc0:
+			if off+1 >= len(msg) {
+				return "", 0, errMalformed
+			}
+			if next < 0 {
+				next = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3fff)
+		default:
+			if off+1+length > len(msg) {
+				return "", 0, errMalformed
+			}
+			label := string(msg[off+1 : off+1+length])
+			labels = append(labels, strings.ReplaceAll(label, ".", "\\."))
+			off += 1 + length
+		}
+	}
+}
+
+func unpackRR(msg []byte, off int) (dnsRR, int, error) {
+	name, off, err := unpackName(msg, off)
+	if err != nil {
+		return dnsRR{}, 0, err
+	}
+	if off+10 > len(msg) {
+		return dnsRR{}, 0, errMalformed
+	}
+	rr := dnsRR{
+		Name:  strings.ToLower(name),
+		Type:  binary.BigEndian.Uint16(msg[off:]),
+		Class: binary.BigEndian.Uint16(msg[off+2:]),
+		TTL:   binary.BigEndian.Uint32(msg[off+4:]),
+	}
+	length := int(binary.BigEndian.Uint16(msg[off+8:]))
+	off += 10
+	if off+length > len(msg) {
+		return dnsRR{}, 0, errMalformed
+	}
+	rr.Raw = append([]byte(nil), msg[off:off+length]...)
+	rr.Data, err = rdataString(msg, off, length, rr.Type)
+	if err != nil {
+		return dnsRR{}, 0, err
+	}
+	return rr, off + length, nil
+}
+
+func rdataString(msg []byte, off, length int, t uint16) (string, error) {
+	rdata := msg[off : off+length]
+	switch t {
+	case typeA:
+		if length != 4 {
+			return "", errMalformed
+		}
+		return net.IP(rdata).String(), nil
+	case typeAAAA:
+		if length != 16 {
+			return "", errMalformed
+		}
+		return net.IP(rdata).String(), nil
+	case typeNS, typeCNAME, typePTR:
+		name, _, err := unpackName(msg, off)
+		return strings.ToLower(name), err
+	case typeMX:
+		if length < 3 {
+			return "", errMalformed
+		}
+		name, _, err := unpackName(msg, off+2)
+		return fmt.Sprintf("%d %s", binary.BigEndian.Uint16(rdata), strings.ToLower(name)), err
+	case typeSRV:
+		if length < 7 {
+			return "", errMalformed
+		}
+		name, _, err := unpackName(msg, off+6)
+		return fmt.Sprintf("%d %d %d %s", binary.BigEndian.Uint16(rdata), binary.BigEndian.Uint16(rdata[2:]),
+			binary.BigEndian.Uint16(rdata[4:]), strings.ToLower(name)), err
+	case typeSOA:
+		mname, next, err := unpackName(msg, off)
+		if err != nil {
+			return "", err
+		}
+		rname, next, err := unpackName(msg, next)
+		if err != nil {
+			return "", err
+		}
+		if next+20 > off+length {
+			return "", errMalformed
+		}
+		v := msg[next:]
+		return fmt.Sprintf("%s %s %d %d %d %d %d", strings.ToLower(mname), strings.ToLower(rname),
+			binary.BigEndian.Uint32(v), binary.BigEndian.Uint32(v[4:]), binary.BigEndian.Uint32(v[8:]),
+			binary.BigEndian.Uint32(v[12:]), binary.BigEndian.Uint32(v[16:])), nil
+	case typeTXT:
+		var sb strings.Builder
+		for i := 0; i < len(rdata); {
+			n := int(rdata[i])
+			if i+1+n > len(rdata) {
+				return "", errMalformed
+			}
+			sb.Write(rdata[i+1 : i+1+n])
+			i += 1 + n
+		}
+		return sb.String(), nil
+	case typeCAA:
+		if length < 2 || 2+int(rdata[1]) > length {
+			return "", errMalformed
+		}
+		tagEnd := 2 + int(rdata[1])
+		return fmt.Sprintf("%d %s %q", rdata[0], rdata[2:tagEnd], rdata[tagEnd:]), nil
+	case typeSVCB, typeHTTPS:
+		r, err := unpackSVCB(rdata)
+		if err != nil {
+			return "", err
+		}
+		return r.String(), nil
+	default:
+		return fmt.Sprintf("\\# %d %s", length, hex.EncodeToString(rdata)), nil
+	}
+}
@@ -0,0 +1,231 @@
+package pig
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+)
+
+//go:embed dnsbl.json
+var dnsblJSON []byte
+
+const dnsblVersion = 1
+
+type dnsblList struct {
+	Zone     string            `json:"zone"`
+	Name     string            `json:"name"`
+	Type     string            `json:"type"`
+	IPv6     bool              `json:"ipv6,omitempty"`
+	TXT      bool              `json:"txt,omitempty"`
+	Bitmask  bool              `json:"bitmask,omitempty"`
+	Disabled bool              `json:"disabled,omitempty"`
+	Codes    map[string]string `json:"codes,omitempty"`
+	Refused  map[string]string `json:"refused,omitempty"`
+}
+
+type dnsblConfig struct {
+	Version int         `json:"version"`
+	Lists   []dnsblList `json:"lists"`
+}
+
+type BlocklistResult struct {
+	List    string   `json:"list"`
+	Zone    string   `json:"zone"`
+	Target  string   `json:"target"`
+	Listed  bool     `json:"listed"`
+	Refused bool     `json:"refused,omitempty"`
+	Error   string   `json:"error,omitempty"`
+	Score   *int     `json:"score,omitempty"`
+	Answers []string `json:"answers,omitempty"`
+	Reasons []string `json:"reasons,omitempty"`
+	TXT     []string `json:"txt,omitempty"`
+}
+
+// loadDNSBLConfig reads the embedded list config followed by each override
+// file. An override list with the same zone replaces the earlier one, and
+// "disabled": true removes it.
+func loadDNSBLConfig(paths []string) ([]dnsblList, error) {
+	lists, err := parseDNSBLConfig(dnsblJSON)
+	if err != nil {
+		return nil, fmt.Errorf("embedded dnsbl.json: %w", err)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		override, err := parseDNSBLConfig(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for _, list := range override {
+			replaced := false
+			for i := range lists {
+				if lists[i].Zone == list.Zone {
+					lists[i] = list
+					replaced = true
+				}
+			}
+			if !replaced {
+				lists = append(lists, list)
+			}
+		}
+	}
+
+	enabled := lists[:0]
+	for _, list := range lists {
+		if !list.Disabled {
+			enabled = append(enabled, list)
+		}
+	}
+	return enabled, nil
+}
+
+func parseDNSBLConfig(data []byte) ([]dnsblList, error) {
+	var config dnsblConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	if config.Version > dnsblVersion {
+		return nil, fmt.Errorf("unsupported DNSBL config version %d", config.Version)
+	}
+	for i := range config.Lists {
+		list := &config.Lists[i]
+		list.Zone = strings.ToLower(strings.Trim(list.Zone, "."))
+		if list.Zone == "" {
+			return nil, fmt.Errorf("list %d has no zone", i)
+		}
+		if list.Name == "" {
+			list.Name = list.Zone
+		}
+		switch list.Type {
+		case "ip", "domain", "score":
+		case "":
+			list.Type = "ip"
+		default:
+			return nil, fmt.Errorf("list %s: unknown type %q", list.Zone, list.Type)
+		}
+	}
+	return config.Lists, nil
+}
+
+func (s *Scanner) queryDNSBL(list *dnsblList, target string) *BlocklistResult {
+	name := fmt.Sprintf("%s.%s", target, list.Zone)
+	if list.Type != "domain" {
+		name = fmt.Sprintf("%s.%s", reverseIP(target), list.Zone)
+	}
+	res := &BlocklistResult{List: list.Name, Zone: list.Zone, Target: target}
+	msg, err := s.query(name, typeA)
+	switch {
+	case err != nil:
+		res.Error = "lookup failed: " + err.Error()
+		return res
+	case msg.Rcode == rcodeRefused:
+		res.Error = "lookup refused by the resolver"
+		return res
+	case msg.Rcode != rcodeSuccess && msg.Rcode != rcodeNXDomain:
+		res.Error = "lookup failed with " + rcodeString(msg.Rcode)
+		return res
+	}
+	answers := []string{}
+	for _, rr := range msg.answers(name, typeA) {
+		answers = append(answers, rr.Data)
+	}
+	if len(answers) < 1 {
+		return res
+	}
+	sort.Strings(answers)
+	res.Answers = answers
+
+	for _, answer := range answers {
+		ip := net.ParseIP(answer)
+		if ip == nil || ip.To4() == nil || ip.To4()[0] != 127 {
+			// Answers outside 127.0.0.0/8 come from a wildcarded or expired zone, not a listing.
+			res.Refused = true
+			res.Reasons = append(res.Reasons, "unexpected answer "+answer+", the list may be defunct")
+			continue
+		}
+		if reason, ok := list.Refused[answer]; ok {
+			res.Refused = true
+			res.Reasons = append(res.Reasons, reason)
+			continue
+		}
+		if list.Type == "score" {
+			score := int(ip.To4()[3])
+			res.Score = &score
+			continue
+		}
+		res.Listed = true
+		res.Reasons = append(res.Reasons, list.meaning(ip.To4())...)
+	}
+
+	if res.Listed && list.TXT {
+		res.TXT = s.lookupTXT(name)
+	}
+	return res
+}
+
+func (list *dnsblList) meaning(ip net.IP) []string {
+	if reason, ok := list.Codes[ip.String()]; ok {
+		return []string{reason}
+	}
+	if list.Bitmask {
+		reasons := []string{}
+		for code, reason := range list.Codes {
+			bit := net.ParseIP(code)
+			if bit != nil && bit.To4() != nil && ip[3]&bit.To4()[3] != 0 {
+				reasons = append(reasons, reason)
+			}
+		}
+		if len(reasons) > 0 {
+			sort.Strings(reasons)
+			return reasons
+		}
+	}
+	return []string{"return code " + ip.String()}
+}
+
+// Blocklists checks ip against the address-based lists and returns the
+// listings, refusals, scores and failed lookups.
+func (s *Scanner) Blocklists(ip net.IP) []*BlocklistResult {
+	results := []*BlocklistResult{}
+	lists := s.dnsbl
+	for i := range lists {
+		if lists[i].Type == "domain" || (ip.To4() == nil && !lists[i].IPv6) {
+			continue
+		}
+		if res := s.queryDNSBL(&lists[i], ip.String()); res.Listed || res.Refused || res.Score != nil || res.Error != "" {
+			results = append(results, res)
+		}
+	}
+	return results
+}
+
+type DomainBlocklistReport struct {
+	Lists   []string           `json:"lists"`
+	Targets []string           `json:"targets"`
+	Results []*BlocklistResult `json:"results,omitempty"`
+}
+
+// DomainBlocklists checks hostnames, usually a domain and its MX and NS
+// hosts, against the domain-based lists.
+func (s *Scanner) DomainBlocklists(targets []string) *DomainBlocklistReport {
+	report := &DomainBlocklistReport{Targets: targets}
+	lists := s.dnsbl
+	for i := range lists {
+		if lists[i].Type != "domain" {
+			continue
+		}
+		report.Lists = append(report.Lists, lists[i].Name)
+		for _, target := range targets {
+			if res := s.queryDNSBL(&lists[i], target); res.Listed || res.Refused || res.Error != "" {
+				report.Results = append(report.Results, res)
+			}
+		}
+	}
+	return report
+}
@@ -0,0 +1,108 @@
+package pig
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testDNSBLConfig = `{
+  "version": 1,
+  "lists": [
+    {"zone": "listed.example", "name": "Listed", "codes": {"127.0.0.2": "spam source"}},
+    {"zone": "clean.example", "name": "Clean"},
+    {"zone": "policy.example", "name": "Policy", "refused": {"127.255.255.254": "query via a public resolver"}},
+    {"zone": "refused.example", "name": "Refused"},
+    {"zone": "broken.example", "name": "Broken"}
+  ]
+}`
+
+func TestBlocklists(t *testing.T) {
+	srv := newTestServer(t, false,
+		"1.2.0.192.listed.example 300 A 127.0.0.2",
+		"1.2.0.192.policy.example 300 A 127.255.255.254",
+	)
+	srv.fail("1.2.0.192.refused.example", "A", rcodeRefused)
+	srv.fail("1.2.0.192.broken.example", "A", rcodeServFail)
+
+	path := filepath.Join(t.TempDir(), "dnsbl.json")
+	if err := os.WriteFile(path, []byte(testDNSBLConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := newTestScanner(t, srv, Options{DNSBLFiles: []string{path}})
+
+	got := map[string]*BlocklistResult{}
+	for _, res := range s.Blocklists(net.ParseIP("192.0.2.1")) {
+		got[res.List] = res
+	}
+	if res := got["Listed"]; res == nil || !res.Listed || strings.Join(res.Reasons, ",") != "spam source" {
+		t.Errorf("Listed: got %+v, want a listing for spam source", res)
+	}
+	if res := got["Clean"]; res != nil {
+		t.Errorf("Clean: got %+v, want no result", res)
+	}
+	if res := got["Policy"]; res == nil || !res.Refused || res.Listed {
+		t.Errorf("Policy: got %+v, want a refusal code", res)
+	}
+	if res := got["Refused"]; res == nil || res.Listed || !strings.Contains(res.Error, "refused") {
+		t.Errorf("Refused: got %+v, want a refused lookup", res)
+	}
+	if res := got["Broken"]; res == nil || res.Listed || !strings.Contains(res.Error, "SERVFAIL") {
+		t.Errorf("Broken: got %+v, want a failed lookup", res)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	for _, query := range srv.queries {
+		if !strings.HasSuffix(query, " A") && !strings.HasSuffix(query, " TXT") {
+			t.Errorf("blocklist check sent %s, want only A and TXT queries", query)
+		}
+	}
+}
+
+func TestDiffIgnoresFailedBlocklistLookups(t *testing.T) {
+	listed := &BlocklistResult{List: "Listed", Zone: "listed.example", Target: "192.0.2.1", Listed: true}
+	failed := &BlocklistResult{List: "Listed", Zone: "listed.example", Target: "192.0.2.1", Error: "lookup refused by the resolver"}
+	report := func(res *BlocklistResult) *Report {
+		r := &Report{Domain: "example.com", Sections: []string{"addresses", "blocklists"}}
+		info := &AddressInfo{IP: net.ParseIP("192.0.2.1")}
+		if res != nil {
+			info.Blocklists = []*BlocklistResult{res}
+		}
+		r.Addresses = []*AddressInfo{info}
+		return r
+	}
+
+	if d := Diff(report(listed), report(failed)); len(d.Delisted) != 0 {
+		t.Errorf("a failed lookup was reported as delisted: %+v", d.Delisted)
+	}
+	if d := Diff(report(listed), report(nil)); len(d.Delisted) != 1 {
+		t.Errorf("a listing that went away was not reported: %+v", d)
+	}
+	if d := Diff(report(failed), report(listed)); len(d.Listed) != 1 {
+		t.Errorf("a new listing was not reported: %+v", d)
+	}
+}
+
+func TestDiffMXBlocklists(t *testing.T) {
+	report := func(listed bool) *Report {
+		return &Report{Domain: "example.com", Sections: []string{"mx", "blocklists"}, MX: []MXInfo{{
+			Host:       "mail.example.com.",
+			Addresses:  []net.IP{net.ParseIP("192.0.2.25")},
+			Blocklists: []*BlocklistResult{{List: "Listed", Zone: "listed.example", Target: "192.0.2.25", Listed: listed}},
+		}}}
+	}
+
+	d := Diff(report(false), report(true))
+	if len(d.Listed) != 1 || d.Listed[0].Target != "192.0.2.25" {
+		t.Errorf("a listed mail server address was not reported: %+v", d)
+	}
+	if d := Diff(report(true), report(false)); len(d.Delisted) != 1 {
+		t.Errorf("a delisted mail server address was not reported: %+v", d)
+	}
+	if d := Diff(report(true), report(true)); !d.Empty() {
+		t.Errorf("an unchanged listing gave diff %+v", d)
+	}
+}
@@ -0,0 +1,30 @@
+package pig
+
+type DNSSECStatus struct {
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// DNSSEC asks the resolver for the domain's SOA with the DO bit set. An
+// AD flag means the answer validated. A SERVFAIL that goes away when
+// validation is disabled with CD means the signatures are bogus.
+func (s *Scanner) DNSSEC(domain string) *DNSSECStatus {
+	msg, err := s.exchange(domain, typeSOA, queryDO)
+	if err != nil {
+		return &DNSSECStatus{Status: "unknown", Detail: err.Error()}
+	}
+	switch {
+	case msg.Rcode == rcodeServFail:
+		cd, err := s.exchange(domain, typeSOA, queryDO|queryCD)
+		if err == nil && cd.Rcode != rcodeServFail {
+			return &DNSSECStatus{Status: "bogus", Detail: "SERVFAIL unless validation is disabled"}
+		}
+		return &DNSSECStatus{Status: "unknown", Detail: "resolver returned SERVFAIL"}
+	case msg.AuthenticData:
+		return &DNSSECStatus{Status: "secure"}
+	case !msg.RecursionAvailable:
+		return &DNSSECStatus{Status: "unknown", Detail: "resolver does not offer recursion"}
+	default:
+		return &DNSSECStatus{Status: "insecure", Detail: "unsigned, or the resolver does not validate"}
+	}
+}
@@ -0,0 +1,145 @@
+package pig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"strings"
+	"time"
+)
+
+// dotPort is the DNS over TLS port of RFC 7858.
+const dotPort = "853"
+
+// A TransportReport lists the encrypted transports a domain advertises:
+// the HTTPS records of the domain, the SVCB records at _dns.<domain> and
+// _dns.<nameserver> (RFC 9461), and, under the Active profile, whether
+// each nameserver address answers DNS over TLS.
+type TransportReport struct {
+	HTTPS []SVCBRecord `json:"https"`
+	SVCB  []SVCBRecord `json:"svcb"`
+	DoT   []DoTCheck   `json:"dot,omitempty"`
+}
+
+// A DoTCheck is one DNS over TLS probe of a nameserver address. Verified
+// means its certificate is valid for the nameserver's name. Answered means
+// it returned a response to an SOA query for the domain over TLS.
+type DoTCheck struct {
+	Server        string `json:"server"`
+	Address       string `json:"address"`
+	Connect       bool   `json:"connect"`
+	Handshake     bool   `json:"handshake"`
+	TLSVersion    string `json:"tls_version,omitempty"`
+	ALPN          string `json:"alpn,omitempty"`
+	Verified      bool   `json:"verified"`
+	CertError     string `json:"cert_error,omitempty"`
+	Answered      bool   `json:"answered"`
+	Authoritative bool   `json:"authoritative,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+var tlsVersions = map[uint16]string{
+	tls.VersionTLS10: "TLS 1.0",
+	tls.VersionTLS11: "TLS 1.1",
+	tls.VersionTLS12: "TLS 1.2",
+	tls.VersionTLS13: "TLS 1.3",
+}
+
+// Transports looks up the HTTPS and SVCB records of domain and its
+// nameservers through the resolver. The DoT probes of the nameservers are
+// only sent under the Active profile.
+func (s *Scanner) Transports(domain string, nameservers []string) *TransportReport {
+	ns := []NSInfo{}
+	for _, host := range nameservers {
+		info := NSInfo{Host: fqdn(host)}
+		if s.profile == Active {
+			info.Addresses = s.lookupIP(host)
+		}
+		ns = append(ns, info)
+	}
+	return s.transports(domain, ns, nil)
+}
+
+func (s *Scanner) transports(domain string, nameservers []NSInfo, probes *probeLog) *TransportReport {
+	t := &TransportReport{HTTPS: s.svcb(domain, typeHTTPS), SVCB: []SVCBRecord{}}
+	if t.HTTPS == nil {
+		t.HTTPS = []SVCBRecord{}
+	}
+	t.SVCB = append(t.SVCB, s.svcb("_dns."+strings.TrimSuffix(domain, "."), typeSVCB)...)
+	for _, ns := range nameservers {
+		t.SVCB = append(t.SVCB, s.svcb("_dns."+strings.TrimSuffix(ns.Host, "."), typeSVCB)...)
+	}
+	if s.profile != Active {
+		return t
+	}
+	for _, ns := range nameservers {
+		for _, ip := range ns.Addresses {
+			t.DoT = append(t.DoT, s.probeDoT(domain, ns.Host, ip, probes))
+		}
+	}
+	return t
+}
+
+// probeDoT connects to port 853 of a nameserver address, completes a TLS
+// handshake with the nameserver's name as SNI, checks the certificate
+// against the roots of Options.TLSConfig or the system, and asks for the
+// domain's SOA over the connection.
+func (s *Scanner) probeDoT(domain, host string, ip net.IP, probes *probeLog) DoTCheck {
+	serverName := strings.TrimSuffix(host, ".")
+	check := DoTCheck{Server: host, Address: net.JoinHostPort(ip.String(), dotPort)}
+	probes.add("dot", check.Address)
+
+	conn, err := net.DialTimeout("tcp", check.Address, s.timeout)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	defer conn.Close()
+	check.Connect = true
+
+	// The certificate is checked separately so that a server with a bad
+	// one still has its handshake and answer recorded.
+	tlsConn := tls.Client(conn, &tls.Config{ServerName: serverName, InsecureSkipVerify: true, NextProtos: []string{"dot"}})
+	tlsConn.SetDeadline(time.Now().Add(s.timeout))
+	if err := tlsConn.Handshake(); err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	state := tlsConn.ConnectionState()
+	check.Handshake = true
+	check.TLSVersion = tlsVersions[state.Version]
+	check.ALPN = state.NegotiatedProtocol
+
+	opts := x509.VerifyOptions{DNSName: serverName, Intermediates: x509.NewCertPool()}
+	if s.opts.TLSConfig != nil {
+		opts.Roots = s.opts.TLSConfig.RootCAs
+	}
+	for _, cert := range state.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	if _, err := state.PeerCertificates[0].Verify(opts); err != nil {
+		check.CertError = err.Error()
+	} else {
+		check.Verified = true
+	}
+
+	query, id, err := packQuery(domain, typeSOA, 0)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	start := time.Now()
+	msg, err := streamExchange(tlsConn, query, id, s.timeout)
+	if s.log != nil {
+		l := newLookup(domain, typeSOA, msg, err, time.Since(start))
+		l.Server = "tls://" + check.Address
+		s.log.add(l)
+	}
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	check.Answered = true
+	check.Authoritative = msg.Authoritative
+	return check
+}
@@ -0,0 +1,119 @@
+package pig
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// EnumResult is a name found by Enumerate, with its CNAME and addresses.
+type EnumResult struct {
+	Host  string   `json:"host"`
+	CNAME string   `json:"cname,omitempty"`
+	IPs   []net.IP `json:"ips,omitempty"`
+}
+
+// Wildcard holds the addresses and CNAME targets that random names under a
+// domain resolve to. Both are empty when the domain has no wildcard record.
+type Wildcard struct {
+	IPs    map[string]bool
+	CNAMEs map[string]bool
+}
+
+func randomLabel() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "pig-" + hex.EncodeToString(b)
+}
+
+// DetectWildcard resolves a few random labels under domain to learn the
+// answers a wildcard record gives.
+func (s *Scanner) DetectWildcard(domain string) Wildcard {
+	wc := Wildcard{IPs: make(map[string]bool), CNAMEs: make(map[string]bool)}
+	for i := 0; i < 3; i++ {
+		host := randomLabel() + "." + domain
+		for _, ip := range s.lookupIP(host) {
+			wc.IPs[ip.String()] = true
+		}
+		if cname := s.lookupCNAME(host); cname != "" {
+			wc.CNAMEs[cname] = true
+		}
+	}
+	return wc
+}
+
+// Matches reports whether res is an answer of the wildcard: its CNAME is a
+// wildcard target, or every one of its addresses is a wildcard address.
+func (wc Wildcard) Matches(res EnumResult) bool {
+	if res.CNAME != "" && wc.CNAMEs[res.CNAME] {
+		return true
+	}
+	if len(wc.IPs) == 0 || len(res.IPs) == 0 {
+		return false
+	}
+	for _, ip := range res.IPs {
+		if !wc.IPs[ip.String()] {
+			return false
+		}
+	}
+	return true
+}
+
+// Enumerate resolves each label under domain with the given number of
+// workers and queries per second, returning the names that exist.
+func (s *Scanner) Enumerate(domain string, labels []string, workers, rate int) []EnumResult {
+	if workers < 1 {
+		workers = 1
+	}
+	if rate < 1 {
+		rate = 1
+	}
+	// Each host takes three queries, A, AAAA and CNAME, and each waits
+	// for its own tick. Rates above 1e9 get the shortest tick there is.
+	interval := time.Second / time.Duration(rate)
+	if interval < time.Nanosecond {
+		interval = time.Nanosecond
+	}
+	limiter := time.NewTicker(interval)
+	defer limiter.Stop()
+	limited := *s
+	limited.limit = limiter.C
+
+	jobs := make(chan string)
+	found := make(chan EnumResult)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for host := range jobs {
+				res := EnumResult{Host: host, IPs: limited.lookupIP(host), CNAME: limited.lookupCNAME(host)}
+				if len(res.IPs) < 1 && res.CNAME == "" {
+					continue
+				}
+				found <- res
+			}
+		}()
+	}
+
+	go func() {
+		for _, label := range labels {
+			jobs <- label + "." + domain
+		}
+		close(jobs)
+		wg.Wait()
+		close(found)
+	}()
+
+	results := []EnumResult{}
+	for res := range found {
+		results = append(results, res)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Host < results[j].Host
+	})
+	return results
+}
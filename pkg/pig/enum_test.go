@@ -0,0 +1,72 @@
+package pig
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestEnumerate(t *testing.T) {
+	srv := newTestServer(t, false,
+		"www.example.com 300 A 192.0.2.1",
+		"mail.example.com 300 AAAA 2001:db8::25",
+		"blog.example.com 300 CNAME hosting.example.net",
+		"hosting.example.net 300 A 192.0.2.2",
+	)
+	s := newTestScanner(t, srv, Options{})
+
+	const rate = 50
+	labels := []string{"www", "mail", "blog", "missing"}
+	start := time.Now()
+	results := s.Enumerate("example.com", labels, 4, rate)
+	elapsed := time.Since(start)
+
+	hosts := []string{}
+	for _, res := range results {
+		hosts = append(hosts, res.Host)
+	}
+	if !equalStrings(hosts, []string{"blog.example.com", "mail.example.com", "www.example.com"}) {
+		t.Errorf("found %v", hosts)
+	}
+	if results[0].CNAME != "hosting.example.net." {
+		t.Errorf("blog CNAME = %q, want hosting.example.net.", results[0].CNAME)
+	}
+
+	queries := srv.count()
+	if queries != 3*len(labels) {
+		t.Errorf("sent %d queries for %d labels, want %d", queries, len(labels), 3*len(labels))
+	}
+	if want := time.Duration(queries-1) * time.Second / rate; elapsed < want {
+		t.Errorf("%d queries took %s at %d per second, want at least %s", queries, elapsed, rate, want)
+	}
+}
+
+func TestEnumerateHighRate(t *testing.T) {
+	srv := newTestServer(t, false, "www.example.com 300 A 192.0.2.1")
+	s := newTestScanner(t, srv, Options{})
+	if results := s.Enumerate("example.com", []string{"www"}, 1, 2e9); len(results) != 1 {
+		t.Errorf("found %v", results)
+	}
+}
+
+func TestWildcardMatches(t *testing.T) {
+	wc := Wildcard{IPs: map[string]bool{"192.0.2.1": true, "192.0.2.2": true}, CNAMEs: map[string]bool{"parked.example.net.": true}}
+	tests := []struct {
+		res  EnumResult
+		want bool
+	}{
+		{EnumResult{IPs: []net.IP{net.ParseIP("192.0.2.1")}}, true},
+		{EnumResult{IPs: []net.IP{net.ParseIP("192.0.2.2"), net.ParseIP("192.0.2.1")}}, true},
+		{EnumResult{IPs: []net.IP{net.ParseIP("192.0.2.1"), net.ParseIP("192.0.2.3")}}, false},
+		{EnumResult{CNAME: "parked.example.net.", IPs: []net.IP{net.ParseIP("198.51.100.1")}}, true},
+		{EnumResult{CNAME: "www.example.net."}, false},
+	}
+	for _, tt := range tests {
+		if got := wc.Matches(tt.res); got != tt.want {
+			t.Errorf("Matches(%+v) = %v, want %v", tt.res, got, tt.want)
+		}
+	}
+	if (Wildcard{}).Matches(EnumResult{IPs: []net.IP{net.ParseIP("192.0.2.1")}}) {
+		t.Error("an empty wildcard matched")
+	}
+}
@@ -0,0 +1,126 @@
+package pig
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+// Evidence weights by signal source. A vendor's confidence combines every
+// piece of evidence as 1 - (1-w1)(1-w2)...
+var signalWeights = map[string]float64{
+	"ns":    0.9,
+	"mx":    0.9,
+	"cname": 0.8,
+	"ip":    0.7,
+	"spf":   0.6,
+	"asn":   0.6,
+	"txt":   0.5,
+}
+
+type VendorEvidence struct {
+	Source string `json:"source"`
+	Value  string `json:"value"`
+}
+
+type Vendor struct {
+	Service    string           `json:"service"`
+	Category   string           `json:"category"`
+	Confidence float64          `json:"confidence"`
+	Evidence   []VendorEvidence `json:"evidence"`
+}
+
+type signals struct {
+	cnames []string
+	ips    []net.IP
+	asns   map[string]string
+	ns     []string
+	mx     []string
+	txt    []string
+	spf    []string
+}
+
+// Vendors infers the SaaS and infrastructure vendors a report points at
+// from its CNAMEs, nameservers, MX hosts, SPF includes, TXT tokens and ASNs.
+func (s *Scanner) Vendors(r *Report) []Vendor {
+	return inferVendors(s.services, r.signals())
+}
+
+func inferVendors(db *serviceDB, sig *signals) []Vendor {
+	found := make(map[string]*Vendor)
+	add := func(fp *ServiceFingerprint, source, value string) {
+		if fp == nil {
+			return
+		}
+		v, ok := found[fp.Service]
+		if !ok {
+			v = &Vendor{Service: fp.Service, Category: fp.Category}
+			found[fp.Service] = v
+		}
+		for _, ev := range v.Evidence {
+			if ev.Source == source && ev.Value == value {
+				return
+			}
+		}
+		v.Evidence = append(v.Evidence, VendorEvidence{Source: source, Value: value})
+	}
+
+	for _, host := range sig.cnames {
+		add(db.match(host), "cname", host)
+	}
+	for _, ip := range sig.ips {
+		add(db.matchIP(ip), "ip", ip.String())
+	}
+	for asn, ip := range sig.asns {
+		add(db.matchASN(asn), "asn", fmt.Sprintf("AS%s (%s)", asn, ip))
+	}
+	for _, host := range sig.ns {
+		add(db.match(host), "ns", host)
+	}
+	for _, host := range sig.mx {
+		add(db.match(host), "mx", host)
+	}
+	for _, host := range sig.spf {
+		add(db.match(host), "spf", host)
+	}
+	for _, txt := range sig.txt {
+		add(db.matchTXT(txt), "txt", txt)
+	}
+
+	vendors := []Vendor{}
+	for _, v := range found {
+		miss := 1.0
+		for _, ev := range v.Evidence {
+			miss *= 1 - signalWeights[ev.Source]
+		}
+		v.Confidence = 1 - miss
+		sort.Slice(v.Evidence, func(i, j int) bool {
+			if v.Evidence[i].Source != v.Evidence[j].Source {
+				return v.Evidence[i].Source < v.Evidence[j].Source
+			}
+			return v.Evidence[i].Value < v.Evidence[j].Value
+		})
+		vendors = append(vendors, *v)
+	}
+	sort.Slice(vendors, func(i, j int) bool {
+		if vendors[i].Confidence != vendors[j].Confidence {
+			return vendors[i].Confidence > vendors[j].Confidence
+		}
+		return vendors[i].Service < vendors[j].Service
+	})
+	return vendors
+}
+
+func spfIncludes(spf []string) []string {
+	hosts := []string{}
+	for _, txt := range spf {
+		for _, mech := range strings.Fields(txt)[1:] {
+			mech = strings.TrimLeft(mech, "+-~?")
+			if strings.HasPrefix(mech, "include:") || strings.HasPrefix(mech, "redirect=") {
+				hosts = append(hosts, mech[strings.IndexAny(mech, ":=")+1:])
+			}
+		}
+	}
+	return hosts
+}
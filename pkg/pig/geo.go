@@ -0,0 +1,188 @@
+package pig
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Geolocation struct {
+	City      string  `json:"city"`
+	Region    string  `json:"region"`
+	Country   string  `json:"country"`
+	Latitude  float64 `json:"latitude,omitempty"`
+	Longitude float64 `json:"longitude,omitempty"`
+	Postal    string  `json:"postal,omitempty"`
+	TimeZone  string  `json:"timezone,omitempty"`
+	Org       string  `json:"org,omitempty"`
+}
+
+type GeoProvider interface {
+	Locate(ip net.IP) (*Geolocation, error)
+}
+
+var defaultGeoIPPaths = []string{
+	"/usr/share/GeoIP/GeoLite2-City.mmdb",
+	"/var/lib/GeoIP/GeoLite2-City.mmdb",
+	"/usr/local/share/GeoIP/GeoLite2-City.mmdb",
+	"/usr/share/GeoIP/GeoLite2-Country.mmdb",
+	"/var/lib/GeoIP/GeoLite2-Country.mmdb",
+	"/usr/local/share/GeoIP/GeoLite2-Country.mmdb",
+}
+
+// ErrNoGeoIPDatabase is returned by NewMMDBProvider when no paths are given
+// and none of the usual GeoLite2 locations exist.
+var ErrNoGeoIPDatabase = errors.New("no GeoIP database found")
+
+// MMDBProvider locates addresses in local MaxMind DB files such as
+// GeoLite2 City, Country or ASN.
+type MMDBProvider struct {
+	readers []*mmdbReader
+}
+
+// NewMMDBProvider opens the given databases, or the first GeoLite2 database
+// found in the usual locations when paths is empty. Later files fill in
+// fields the earlier ones lack.
+func NewMMDBProvider(paths []string) (*MMDBProvider, error) {
+	if len(paths) == 0 {
+		for _, path := range defaultGeoIPPaths {
+			if _, err := os.Stat(path); err == nil {
+				paths = []string{path}
+				break
+			}
+		}
+	}
+	if len(paths) == 0 {
+		return nil, ErrNoGeoIPDatabase
+	}
+	p := &MMDBProvider{}
+	for _, path := range paths {
+		r, err := openMMDB(path)
+		if err != nil {
+			return nil, err
+		}
+		p.readers = append(p.readers, r)
+	}
+	return p, nil
+}
+
+func (p *MMDBProvider) Locate(ip net.IP) (*Geolocation, error) {
+	geo := &Geolocation{}
+	found := false
+	for _, r := range p.readers {
+		rec, err := r.lookup(ip)
+		if err != nil {
+			return nil, err
+		}
+		if rec == nil {
+			continue
+		}
+		found = true
+		setString(&geo.City, mmdbPath(rec, "city", "names", "en"))
+		setString(&geo.Region, mmdbPath(rec, "subdivisions", 0, "names", "en"))
+		setString(&geo.Country, mmdbPath(rec, "country", "iso_code"))
+		setString(&geo.Postal, mmdbPath(rec, "postal", "code"))
+		setString(&geo.TimeZone, mmdbPath(rec, "location", "time_zone"))
+		if lat, ok := mmdbPath(rec, "location", "latitude").(float64); ok {
+			geo.Latitude = lat
+		}
+		if lon, ok := mmdbPath(rec, "location", "longitude").(float64); ok {
+			geo.Longitude = lon
+		}
+		if asn := mmdbUint(mmdbPath(rec, "autonomous_system_number")); asn != 0 {
+			geo.Org = fmt.Sprintf("AS%d", asn)
+			if name, ok := mmdbPath(rec, "autonomous_system_organization").(string); ok && name != "" {
+				geo.Org += " " + name
+			}
+		}
+	}
+	if !found {
+		return nil, nil
+	}
+	return geo, nil
+}
+
+func setString(dst *string, v interface{}) {
+	if s, ok := v.(string); ok && *dst == "" {
+		*dst = s
+	}
+}
+
+// IPInfoProvider looks addresses up with the ipinfo.io API.
+type IPInfoProvider struct {
+	token  string
+	client *http.Client
+}
+
+// NewIPInfoProvider returns a provider using token, which may be empty for
+// the rate-limited anonymous API.
+func NewIPInfoProvider(token string) *IPInfoProvider {
+	return &IPInfoProvider{token: token, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+type ipinfoResponse struct {
+	City     string `json:"city"`
+	Region   string `json:"region"`
+	Country  string `json:"country"`
+	Loc      string `json:"loc"`
+	Postal   string `json:"postal"`
+	Timezone string `json:"timezone"`
+	Org      string `json:"org"`
+}
+
+func (p *IPInfoProvider) Locate(ip net.IP) (*Geolocation, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("https://ipinfo.io/%s/json", ip.String()), nil)
+	if err != nil {
+		return nil, err
+	}
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ipinfo.io returned %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var info ipinfoResponse
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, err
+	}
+
+	geo := &Geolocation{
+		City:     info.City,
+		Region:   info.Region,
+		Country:  info.Country,
+		Postal:   info.Postal,
+		TimeZone: info.Timezone,
+		Org:      info.Org,
+	}
+	if lat, lon, ok := strings.Cut(info.Loc, ","); ok {
+		geo.Latitude, _ = strconv.ParseFloat(lat, 64)
+		geo.Longitude, _ = strconv.ParseFloat(lon, 64)
+	}
+	return geo, nil
+}
+
+// Geolocate returns nil without an error when geolocation is disabled or
+// the address is not in the database.
+func (s *Scanner) Geolocate(ip net.IP) (*Geolocation, error) {
+	if s.opts.Geo == nil {
+		return nil, nil
+	}
+	return s.opts.Geo.Locate(ip)
+}
@@ -0,0 +1,419 @@
+package pig
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A LintIssue is a problem found in a zone file, at the line of the record
+// it is about.
+type LintIssue struct {
+	File        string   `json:"file"`
+	Line        int      `json:"line"`
+	Name        string   `json:"name,omitempty"`
+	ID          string   `json:"id"`
+	Severity    Severity `json:"severity"`
+	Title       string   `json:"title"`
+	Detail      string   `json:"detail,omitempty"`
+	Remediation string   `json:"remediation,omitempty"`
+}
+
+// lintChecks are the checks that only make sense with the whole zone at
+// hand. The others reuse the scanner's rules, and are skipped when their
+// rule is disabled.
+var lintChecks = map[string]Rule{
+	"zone-syntax": {
+		Severity:    SeverityHigh,
+		Title:       "Unreadable zone file entry",
+		Remediation: "Fix the entry. Nameservers refuse to load a zone with syntax errors.",
+	},
+	"soa-missing": {
+		Severity:    SeverityHigh,
+		Title:       "No SOA record at the zone apex",
+		Remediation: "Add an SOA record for the origin.",
+	},
+	"ns-missing": {
+		Severity:    SeverityHigh,
+		Title:       "No NS records at the zone apex",
+		Remediation: "Add an NS record for each nameserver of the zone.",
+	},
+	"target-cname": {
+		Severity:    SeverityMedium,
+		Title:       "Target is a CNAME",
+		Remediation: "Point the record at the name the CNAME leads to. MX, NS and SRV targets must not be aliases (RFC 2181 section 10.3).",
+	},
+	"target-no-address": {
+		Severity:    SeverityMedium,
+		Title:       "Target has no address",
+		Remediation: "Add A or AAAA records for the target, or point the record at a host that has them.",
+	},
+	"glue-missing": {
+		Severity:    SeverityHigh,
+		Title:       "Missing glue",
+		Remediation: "Add A or AAAA records for the nameserver, which resolvers need before they can ask it anything.",
+	},
+	"ttl-mismatch": {
+		Severity:    SeverityLow,
+		Title:       "TTLs differ within an RRset",
+		Remediation: "Give every record of the same name and type the same TTL (RFC 2181 section 5.2).",
+	},
+	"ttl-outlier": {
+		Severity:    SeverityLow,
+		Title:       "Unusual TTL",
+		Remediation: "Use a TTL between a few minutes and a day unless there is a reason not to.",
+	},
+}
+
+// recordChecks are the rules Lint checks record by record. Their findings
+// from the apex report are dropped so they are not raised twice.
+var recordChecks = map[string]bool{
+	"spf-syntax": true, "spf-too-many-lookups": true, "spf-multiple": true,
+	"dmarc-syntax": true, "caa-syntax": true, "cname-conflict": true, "cname-loop": true,
+}
+
+// ttlExempt are the types whose TTLs are set by convention rather than by
+// how often the data changes.
+var ttlExempt = map[string]bool{"SOA": true, "NS": true, "DS": true, "DNSKEY": true, "RRSIG": true}
+
+type linter struct {
+	s      *Scanner
+	zone   *Zone
+	names  map[string][]ZoneRecord
+	issues []LintIssue
+}
+
+// Lint checks a zone without sending any queries: the same SPF, DMARC,
+// CAA and CNAME analysis pig applies to live answers, the apex rules of
+// the scanner, and checks of targets, glue and TTLs that need the whole
+// zone. Issues are sorted by file and line.
+func (s *Scanner) Lint(z *Zone) []LintIssue {
+	l := &linter{s: s, zone: z, names: map[string][]ZoneRecord{}, issues: []LintIssue{}}
+	for _, rec := range z.Records {
+		l.names[rec.Name] = append(l.names[rec.Name], rec)
+	}
+	for _, e := range z.Errors {
+		l.add(ZoneRecord{File: e.File, Line: e.Line}, "zone-syntax", e.Message)
+	}
+
+	l.apex()
+	l.targets()
+	l.ttls()
+	l.text()
+	l.cnames()
+	l.rules()
+
+	sort.SliceStable(l.issues, func(i, j int) bool {
+		a, b := l.issues[i], l.issues[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	return l.issues
+}
+
+// add records an issue at rec, taking its severity and wording from the
+// scanner's rule or the lint check with that id.
+func (l *linter) add(rec ZoneRecord, id, detail string) {
+	rule, ok := lintChecks[id]
+	for _, r := range l.s.rules {
+		if r.ID == id {
+			rule, ok = r, true
+		}
+	}
+	if !ok {
+		return
+	}
+	l.issues = append(l.issues, LintIssue{
+		File:        rec.File,
+		Line:        rec.Line,
+		Name:        rec.Name,
+		ID:          id,
+		Severity:    rule.Severity,
+		Title:       rule.Title,
+		Detail:      detail,
+		Remediation: rule.Remediation,
+	})
+}
+
+// find returns the records of a type at name.
+func (l *linter) find(name, rtype string) []ZoneRecord {
+	found := []ZoneRecord{}
+	for _, rec := range l.names[name] {
+		if rec.Type == rtype {
+			found = append(found, rec)
+		}
+	}
+	return found
+}
+
+// first returns where the zone starts: its SOA, else its first record.
+func (l *linter) first() ZoneRecord {
+	if soa := l.find(l.zone.Origin, "SOA"); len(soa) > 0 {
+		return soa[0]
+	}
+	if len(l.zone.Records) > 0 {
+		return l.zone.Records[0]
+	}
+	return ZoneRecord{File: l.zone.File, Line: 1, Record: Record{Name: l.zone.Origin}}
+}
+
+func (l *linter) inZone(name string) bool {
+	origin := l.zone.Origin
+	return origin != "" && (name == origin || strings.HasSuffix(name, "."+origin))
+}
+
+// delegated reports whether name is at or below a delegation to another
+// zone, where this zone only holds glue.
+func (l *linter) delegated(name string) bool {
+	for n := name; l.inZone(n) && n != l.zone.Origin; {
+		if len(l.find(n, "NS")) > 0 {
+			return true
+		}
+		_, n, _ = strings.Cut(n, ".")
+	}
+	return false
+}
+
+func (l *linter) hasAddress(name string) bool {
+	return len(l.find(name, "A")) > 0 || len(l.find(name, "AAAA")) > 0
+}
+
+func (l *linter) apex() {
+	if l.zone.Origin == "" {
+		return
+	}
+	if len(l.find(l.zone.Origin, "SOA")) == 0 {
+		l.add(l.first(), "soa-missing", "")
+	}
+	if len(l.find(l.zone.Origin, "NS")) == 0 {
+		l.add(l.first(), "ns-missing", "")
+	}
+}
+
+// targets checks the hosts MX, NS and SRV records point at, when they are
+// in the zone. Out-of-zone targets would need queries.
+func (l *linter) targets() {
+	for _, rec := range l.zone.Records {
+		target := ""
+		switch rec.Type {
+		case "MX", "SRV":
+			fields := strings.Fields(rec.Data)
+			target = fields[len(fields)-1]
+		case "NS":
+			target = rec.Data
+		}
+		if target == "" || target == "." || !l.inZone(target) {
+			continue
+		}
+		if rec.Type == "NS" && (target == rec.Name || strings.HasSuffix(target, "."+rec.Name)) {
+			if !l.hasAddress(target) {
+				l.add(rec, "glue-missing", fmt.Sprintf("%s is inside %s but has no A or AAAA records", target, rec.Name))
+			}
+			continue
+		}
+		if len(l.find(target, "CNAME")) > 0 {
+			l.add(rec, "target-cname", fmt.Sprintf("%s %s points at %s, which is a CNAME", rec.Type, rec.Data, target))
+			continue
+		}
+		if !l.delegated(target) && !l.hasAddress(target) {
+			l.add(rec, "target-no-address", fmt.Sprintf("%s has no A or AAAA records", target))
+		}
+	}
+}
+
+func (l *linter) ttls() {
+	sets := map[string][]ZoneRecord{}
+	ttls := []uint32{}
+	for _, rec := range l.zone.Records {
+		key := rec.Name + " " + rec.Type
+		if set := sets[key]; len(set) > 0 && set[0].TTL != rec.TTL {
+			l.add(rec, "ttl-mismatch", fmt.Sprintf("%s %s has TTL %d where %s:%d has %d", rec.Name, rec.Type, rec.TTL, set[0].File, set[0].Line, set[0].TTL))
+		}
+		sets[key] = append(sets[key], rec)
+		if !ttlExempt[rec.Type] {
+			ttls = append(ttls, rec.TTL)
+		}
+	}
+	if len(ttls) == 0 {
+		return
+	}
+	sort.Slice(ttls, func(i, j int) bool { return ttls[i] < ttls[j] })
+	median := uint64(ttls[len(ttls)/2])
+
+	for _, rec := range l.zone.Records {
+		if ttlExempt[rec.Type] {
+			continue
+		}
+		ttl := uint64(rec.TTL)
+		switch {
+		case ttl < 60:
+			l.add(rec, "ttl-outlier", fmt.Sprintf("TTL %d is under a minute, so resolvers ask for %s again almost every time", ttl, rec.Name))
+		case ttl > 604800:
+			l.add(rec, "ttl-outlier", fmt.Sprintf("TTL %d is over a week, so changes to %s take that long to reach everyone", ttl, rec.Name))
+		case ttl*100 < median || ttl > median*100:
+			l.add(rec, "ttl-outlier", fmt.Sprintf("TTL %d is far from the zone's usual %d", ttl, median))
+		}
+	}
+}
+
+// text checks SPF and DMARC records wherever they are in the zone.
+func (l *linter) text() {
+	spf := map[string]int{}
+	for _, rec := range l.zone.Records {
+		if rec.Type != "TXT" {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(rec.Data, "v=spf1"):
+			if spf[rec.Name]++; spf[rec.Name] == 2 {
+				l.add(rec, "spf-multiple", rec.Name+" has more than one SPF record")
+			}
+			for _, problem := range spfProblems(rec.Data) {
+				l.add(rec, "spf-syntax", problem)
+			}
+			if n := spfLookups(rec.Data, l.spf); n > spfLookupLimit {
+				l.add(rec, "spf-too-many-lookups", fmt.Sprintf("the record needs %d DNS lookups, more than %d", n, spfLookupLimit))
+			}
+		case strings.HasPrefix(rec.Name, "_dmarc.") && strings.HasPrefix(strings.ToUpper(rec.Data), "V=DMARC"):
+			for _, problem := range dmarcProblems(rec.Data) {
+				l.add(rec, "dmarc-syntax", problem)
+			}
+		}
+	}
+	for _, rec := range l.zone.Records {
+		if rec.Type == "CAA" {
+			for _, problem := range caaProblems(rec.Data) {
+				l.add(rec, "caa-syntax", problem)
+			}
+		}
+	}
+}
+
+// spf returns the SPF records of a name in the zone, or nil for names
+// outside it, which spfLookups then counts as one lookup.
+func (l *linter) spf(domain string) []string {
+	if !l.inZone(domain) {
+		return nil
+	}
+	records := []string{}
+	for _, rec := range l.find(domain, "TXT") {
+		if strings.HasPrefix(rec.Data, "v=spf1") {
+			records = append(records, rec.Data)
+		}
+	}
+	return records
+}
+
+func (l *linter) cnames() {
+	for _, rec := range l.zone.Records {
+		if rec.Type != "CNAME" {
+			continue
+		}
+		cnames := l.find(rec.Name, "CNAME")
+		if cnames[0].Line != rec.Line || cnames[0].File != rec.File {
+			l.add(rec, "cname-conflict", rec.Name+" has more than one CNAME")
+			continue
+		}
+		if rec.Name == l.zone.Origin {
+			l.add(rec, "cname-conflict", rec.Name+" is a CNAME at the zone apex")
+		}
+		others := []string{}
+		for _, other := range l.names[rec.Name] {
+			if other.Type != "CNAME" && other.Type != "RRSIG" && other.Type != "NSEC" && !contains(others, other.Type) {
+				others = append(others, other.Type)
+			}
+		}
+		if len(others) > 0 {
+			l.add(rec, "cname-conflict", rec.Name+" has a CNAME alongside "+strings.Join(others, ", "))
+		}
+
+		seen := map[string]bool{rec.Name: true}
+		for target := rec.Data; ; {
+			if seen[target] || len(seen) > MaxCNAMEChain {
+				l.add(rec, "cname-loop", fmt.Sprintf("the CNAME chain from %s loops or runs past %d hops inside the zone", rec.Name, MaxCNAMEChain))
+				break
+			}
+			next := l.find(target, "CNAME")
+			if len(next) == 0 {
+				break
+			}
+			seen[target] = true
+			target = next[0].Data
+		}
+	}
+}
+
+// rules runs the scanner's rules on a report built from the apex records,
+// and places each finding on the record its first fact is about.
+func (l *linter) rules() {
+	origin := l.zone.Origin
+	if origin == "" || len(l.names[origin]) == 0 {
+		return
+	}
+	r := &Report{Domain: strings.TrimSuffix(origin, "."), Sections: []string{"mx", "ns", "spf", "srv", "txt"}}
+	for _, rec := range l.names[origin] {
+		r.Records = append(r.Records, rec.Record)
+	}
+	for _, data := range r.values("MX") {
+		pref, host, _ := strings.Cut(data, " ")
+		n, _ := strconv.ParseUint(pref, 10, 16)
+		r.MX = append(r.MX, MXInfo{Host: host, Pref: uint16(n), Service: l.s.DetectService(host)})
+	}
+	for _, host := range r.values("NS") {
+		r.NS = append(r.NS, NSInfo{Host: host, Service: l.s.DetectService(host)})
+	}
+	r.TXT = r.values("TXT")
+	r.SPF = l.spf(origin)
+	if len(r.SPF) == 1 {
+		r.SPFLookups = spfLookups(r.SPF[0], l.spf)
+	}
+	for _, rec := range l.find("_dmarc."+origin, "TXT") {
+		if strings.HasPrefix(rec.Data, "v=DMARC1") {
+			r.DMARC = append(r.DMARC, rec.Data)
+		}
+	}
+
+	card := l.s.Score(r)
+	if card == nil {
+		return
+	}
+	for _, f := range card.Findings {
+		if recordChecks[f.ID] {
+			continue
+		}
+		at := l.first()
+		for _, rule := range l.s.rules {
+			if rule.ID != f.ID || len(rule.When) == 0 {
+				continue
+			}
+			name, rtype := origin, ""
+			switch prefix, _, _ := strings.Cut(rule.When[0].Fact, "."); prefix {
+			case "spf":
+				rtype = "TXT"
+			case "dmarc":
+				name, rtype = "_dmarc."+origin, "TXT"
+			case "mx", "ns", "caa":
+				rtype = strings.ToUpper(prefix)
+			}
+			for _, rec := range l.find(name, rtype) {
+				if rtype != "TXT" || strings.HasPrefix(rec.Data, "v=spf1") || strings.HasPrefix(rec.Data, "v=DMARC1") {
+					at = rec
+					break
+				}
+			}
+		}
+		l.add(at, f.ID, strings.Join(f.Evidence, "; "))
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package pig
+
+import (
+	"fmt"
+	"testing"
+)
+
+const lintTestZone = `$ORIGIN example.com.
+$TTL 3600
+@ SOA ns1 hostmaster 1 7200 3600 1209600 300
+  NS ns1
+  MX 10 mail
+  MX 20 mx2
+  TXT "v=spf1 include:_spf.example.net -all"
+  TXT "v=spf1 -all ip4:192.0.2.1"
+  CAA 0 iodef "ftp://example.com/"
+ns1 A 192.0.2.1
+mail CNAME mx1
+mail TXT "hello"
+mx1 A 192.0.2.2
+mx1 30 A 192.0.2.3
+sub NS ns.sub
+loop1 CNAME loop2
+loop2 CNAME loop1
+_dmarc TXT "v=DMARC1; p=bogus"
+cdn NS ns.other.example.
+www.cdn A 192.0.2.4
+srv 3600 SRV 0 0 443 cdn
+`
+
+func lintZone(t *testing.T, zone string) []LintIssue {
+	z, err := ParseZoneFile(writeZone(t, "db.example.com", zone), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewScanner(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.Lint(z)
+}
+
+func TestLint(t *testing.T) {
+	issues := lintZone(t, lintTestZone)
+	got := map[string]bool{}
+	for _, issue := range issues {
+		got[fmt.Sprintf("%d %s", issue.Line, issue.ID)] = true
+	}
+	for _, want := range []string{
+		"5 target-cname",
+		"6 target-no-address",
+		"8 spf-multiple",
+		"8 spf-syntax",
+		"9 caa-syntax",
+		"11 cname-conflict",
+		"14 ttl-mismatch",
+		"14 ttl-outlier",
+		"15 glue-missing",
+		"16 cname-loop",
+		"17 cname-loop",
+		"18 dmarc-syntax",
+	} {
+		if !got[want] {
+			t.Errorf("missing issue %s", want)
+		}
+	}
+	// The SRV target is below the delegation of cdn, so it is not checked.
+	for _, issue := range issues {
+		if issue.ID == "soa-missing" || issue.ID == "ns-missing" || (issue.ID == "target-no-address" && issue.Line != 6) {
+			t.Errorf("unexpected %s at line %d: %s", issue.ID, issue.Line, issue.Detail)
+		}
+	}
+	for i := 1; i < len(issues); i++ {
+		if issues[i].Line < issues[i-1].Line {
+			t.Errorf("issues not sorted by line: %d after %d", issues[i].Line, issues[i-1].Line)
+		}
+	}
+}
+
+func TestLintApex(t *testing.T) {
+	tests := []struct {
+		zone string
+		want []string
+	}{
+		{"$ORIGIN example.com.\n$TTL 300\nwww A 192.0.2.1\n", []string{"3 soa-missing", "3 ns-missing"}},
+		{"$ORIGIN example.com.\n$TTL 300\n@ CNAME example.net.\n  MX 10 mail.example.net.\n", []string{"3 soa-missing", "3 ns-missing", "3 cname-conflict", "3 cname-conflict"}},
+		{"$ORIGIN example.com.\n$TTL 300\nwww A 192.0.2.1\nbad A ::1\n", []string{"3 soa-missing", "3 ns-missing", "4 zone-syntax"}},
+	}
+	for _, tt := range tests {
+		got := []string{}
+		for _, issue := range lintZone(t, tt.zone) {
+			if _, ok := lintChecks[issue.ID]; ok || recordChecks[issue.ID] {
+				got = append(got, fmt.Sprintf("%d %s", issue.Line, issue.ID))
+			}
+		}
+		if !equalStrings(got, tt.want) {
+			t.Errorf("%q: issues %v, want %v", tt.zone, got, tt.want)
+		}
+	}
+}
+
+func TestLintRules(t *testing.T) {
+	issues := lintZone(t, "$ORIGIN example.com.\n$TTL 3600\n@ SOA ns1 hostmaster 1 7200 3600 1209600 300\n  NS ns1\n  TXT \"v=spf1 +all\"\nns1 A 192.0.2.1\n")
+	lines := map[string]int{}
+	for _, issue := range issues {
+		lines[issue.ID] = issue.Line
+	}
+	for id, line := range map[string]int{"spf-pass-all": 5, "ns-single": 4, "dmarc-missing": 3} {
+		if lines[id] != line {
+			t.Errorf("%s at line %d, want %d (issues %+v)", id, lines[id], line, issues)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package pig
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Outcome classifies the answer to one query.
+type Outcome string
+
+const (
+	OutcomeData     Outcome = "data"
+	OutcomeNoData   Outcome = "nodata"
+	OutcomeNXDomain Outcome = "nxdomain"
+	OutcomeServFail Outcome = "servfail"
+	OutcomeRefused  Outcome = "refused"
+	OutcomeTimeout  Outcome = "timeout"
+	OutcomeNetwork  Outcome = "network_error"
+	// OutcomeError is any other response code, such as FORMERR or NOTIMP.
+	OutcomeError Outcome = "error"
+)
+
+// A Lookup is one query and what came back. Server is empty for queries
+// to the resolver. Answers counts the records of the asked type, including
+// those at the end of a CNAME chain. Duration is in nanoseconds in JSON.
+type Lookup struct {
+	Name     string        `json:"name"`
+	Type     string        `json:"type"`
+	Server   string        `json:"server,omitempty"`
+	Outcome  Outcome       `json:"outcome"`
+	Rcode    string        `json:"rcode,omitempty"`
+	Answers  int           `json:"answers,omitempty"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Failed reports whether the lookup got no usable answer. NXDOMAIN and
+// NODATA are answers.
+func (l Lookup) Failed() bool {
+	switch l.Outcome {
+	case OutcomeData, OutcomeNoData, OutcomeNXDomain:
+		return false
+	}
+	return true
+}
+
+func newLookup(name string, qtype uint16, msg *dnsMsg, err error, elapsed time.Duration) Lookup {
+	l := Lookup{Name: strings.ToLower(fqdn(name)), Type: typeString(qtype), Duration: elapsed}
+	if err != nil {
+		l.Error = err.Error()
+	} else {
+		l.Rcode = rcodeString(msg.Rcode)
+		for _, rr := range msg.Answer {
+			if rr.Type == qtype {
+				l.Answers++
+			}
+		}
+	}
+	l.Outcome = classify(msg, err, l.Answers)
+	return l
+}
+
+func classify(msg *dnsMsg, err error, answers int) Outcome {
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return OutcomeTimeout
+		}
+		return OutcomeNetwork
+	}
+	switch msg.Rcode {
+	case rcodeSuccess:
+		if answers > 0 {
+			return OutcomeData
+		}
+		return OutcomeNoData
+	case rcodeNXDomain:
+		return OutcomeNXDomain
+	case rcodeServFail:
+		return OutcomeServFail
+	case rcodeRefused:
+		return OutcomeRefused
+	}
+	return OutcomeError
+}
+
+// A LookupLog collects lookups from any number of goroutines.
+type LookupLog struct {
+	mu      sync.Mutex
+	lookups []Lookup
+	parent  *LookupLog
+}
+
+func (l *LookupLog) add(lookup Lookup) {
+	l.mu.Lock()
+	l.lookups = append(l.lookups, lookup)
+	l.mu.Unlock()
+	if l.parent != nil {
+		l.parent.add(lookup)
+	}
+}
+
+// Lookups returns the recorded lookups in the order they finished.
+func (l *LookupLog) Lookups() []Lookup {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Lookup{}, l.lookups...)
+}
@@ -0,0 +1,306 @@
+package pig
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+)
+
+var mmdbMetadataMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+var errMMDBInvalid = errors.New("invalid MaxMind DB file")
+
+// mmdbReader reads MaxMind DB files such as GeoLite2 City, Country and ASN.
+// See https://maxmind.github.io/MaxMind-DB/ for the format.
+type mmdbReader struct {
+	path         string
+	buf          []byte
+	data         []byte
+	nodeCount    uint
+	recordSize   uint
+	ipVersion    uint
+	databaseType string
+	ipv4Start    uint
+}
+
+func openMMDB(path string) (*mmdbReader, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	start := bytes.LastIndex(buf, mmdbMetadataMarker)
+	if start < 0 {
+		return nil, fmt.Errorf("%s: %w", path, errMMDBInvalid)
+	}
+	metaStart := start + len(mmdbMetadataMarker)
+	meta, _, err := mmdbDecode(buf[metaStart:], 0)
+	if err != nil {
+		return nil, fmt.Errorf("%s: metadata: %w", path, err)
+	}
+	metadata, ok := meta.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", path, errMMDBInvalid)
+	}
+
+	r := &mmdbReader{
+		path:         path,
+		buf:          buf,
+		nodeCount:    mmdbUint(metadata["node_count"]),
+		recordSize:   mmdbUint(metadata["record_size"]),
+		ipVersion:    mmdbUint(metadata["ip_version"]),
+		databaseType: fmt.Sprint(metadata["database_type"]),
+	}
+	if r.recordSize != 24 && r.recordSize != 28 && r.recordSize != 32 {
+		return nil, fmt.Errorf("%s: unsupported record size %d", path, r.recordSize)
+	}
+	treeSize := r.nodeCount * r.recordSize / 4
+	if treeSize+16 > uint(start) {
+		return nil, fmt.Errorf("%s: %w", path, errMMDBInvalid)
+	}
+	r.data = buf[treeSize+16 : start]
+
+	if r.ipVersion == 6 {
+		node := uint(0)
+		for i := 0; i < 96 && node < r.nodeCount; i++ {
+			node = r.record(node, 0)
+		}
+		r.ipv4Start = node
+	}
+	return r, nil
+}
+
+func (r *mmdbReader) record(node, bit uint) uint {
+	size := r.recordSize / 4
+	b := r.buf[node*size : node*size+size]
+	switch r.recordSize {
+	case 24:
+		if bit == 0 {
+			return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3])<<16 | uint(b[4])<<8 | uint(b[5])
+	case 28:
+		if bit == 0 {
+			return uint(b[3]&0xf0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0f)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		if bit == 0 {
+			return uint(binary.BigEndian.Uint32(b))
+		}
+		return uint(binary.BigEndian.Uint32(b[4:]))
+	}
+}
+
+// lookup returns the decoded record for ip, or nil if the database has none.
+func (r *mmdbReader) lookup(ip net.IP) (interface{}, error) {
+	addr := ip.To4()
+	node := uint(0)
+	if addr == nil {
+		if r.ipVersion == 4 {
+			return nil, fmt.Errorf("%s is an IPv4-only database", r.path)
+		}
+		addr = ip.To16()
+	} else if r.ipVersion == 6 {
+		node = r.ipv4Start
+	}
+	if addr == nil {
+		return nil, fmt.Errorf("invalid IP address %v", ip)
+	}
+
+	for i := 0; i < len(addr)*8 && node < r.nodeCount; i++ {
+		bit := uint(addr[i/8]>>(7-uint(i%8))) & 1
+		node = r.record(node, bit)
+	}
+	if node == r.nodeCount {
+		return nil, nil
+	}
+	if node < r.nodeCount {
+		return nil, fmt.Errorf("%s: %w", r.path, errMMDBInvalid)
+	}
+	offset := node - r.nodeCount - 16
+	if offset >= uint(len(r.data)) {
+		return nil, fmt.Errorf("%s: %w", r.path, errMMDBInvalid)
+	}
+	value, _, err := mmdbDecode(r.data, offset)
+	return value, err
+}
+
+// mmdbMaxDepth bounds the nesting of maps, arrays and pointers, so a
+// corrupt file whose pointers form a loop fails instead of recursing
+// until the stack runs out.
+const mmdbMaxDepth = 32
+
+func mmdbDecode(data []byte, offset uint) (interface{}, uint, error) {
+	return mmdbDecodeDepth(data, offset, 0)
+}
+
+func mmdbDecodeDepth(data []byte, offset uint, depth int) (interface{}, uint, error) {
+	if depth > mmdbMaxDepth || offset >= uint(len(data)) {
+		return nil, 0, errMMDBInvalid
+	}
+	ctrl := data[offset]
+	offset++
+	kind := uint(ctrl >> 5)
+
+	if kind == 1 {
+		size := uint(ctrl>>3) & 0x3
+		value := uint(ctrl & 0x7)
+		if offset+size+1 > uint(len(data)) {
+			return nil, 0, errMMDBInvalid
+		}
+		var pointer uint
+		switch size {
+		case 0:
+			pointer = value<<8 | uint(data[offset])
+		case 1:
+			pointer = (value<<16 | uint(data[offset])<<8 | uint(data[offset+1])) + 2048
+		case 2:
+			pointer = (value<<24 | uint(data[offset])<<16 | uint(data[offset+1])<<8 | uint(data[offset+2])) + 526336
+		case 3:
+			pointer = uint(binary.BigEndian.Uint32(data[offset:]))
+		}
+		v, _, err := mmdbDecodeDepth(data, pointer, depth+1)
+		return v, offset + size + 1, err
+	}
+
+	if kind == 0 {
+		if offset >= uint(len(data)) {
+			return nil, 0, errMMDBInvalid
+		}
+		kind = 7 + uint(data[offset])
+		offset++
+	}
+
+	size := uint(ctrl & 0x1f)
+	if size >= 29 {
+		n := size - 28
+		if offset+n > uint(len(data)) {
+			return nil, 0, errMMDBInvalid
+		}
+		extra := uint(0)
+		for i := uint(0); i < n; i++ {
+			extra = extra<<8 | uint(data[offset+i])
+		}
+		offset += n
+		switch n {
+		case 1:
+			size = 29 + extra
+		case 2:
+			size = 285 + extra
+		default:
+			size = 65821 + extra
+		}
+	}
+
+	// Every map entry takes at least two bytes and every array element
+	// one, so a size the rest of the data cannot hold is not allocated.
+	remaining := uint(len(data)) - offset
+	switch kind {
+	case 7:
+		if size > remaining/2 {
+			return nil, 0, errMMDBInvalid
+		}
+		m := make(map[string]interface{}, size)
+		for i := uint(0); i < size; i++ {
+			key, next, err := mmdbDecodeDepth(data, offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			value, next, err := mmdbDecodeDepth(data, next, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			m[fmt.Sprint(key)] = value
+			offset = next
+		}
+		return m, offset, nil
+	case 11:
+		if size > remaining {
+			return nil, 0, errMMDBInvalid
+		}
+		a := make([]interface{}, 0, size)
+		for i := uint(0); i < size; i++ {
+			value, next, err := mmdbDecodeDepth(data, offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			a = append(a, value)
+			offset = next
+		}
+		return a, offset, nil
+	case 14:
+		return size != 0, offset, nil
+	}
+
+	if offset+size > uint(len(data)) {
+		return nil, 0, errMMDBInvalid
+	}
+	b := data[offset : offset+size]
+	offset += size
+	switch kind {
+	case 2:
+		return string(b), offset, nil
+	case 3:
+		if size != 8 {
+			return nil, 0, errMMDBInvalid
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), offset, nil
+	case 4:
+		return append([]byte(nil), b...), offset, nil
+	case 5, 6, 9:
+		v := uint64(0)
+		for _, c := range b {
+			v = v<<8 | uint64(c)
+		}
+		return v, offset, nil
+	case 8:
+		v := uint32(0)
+		for _, c := range b {
+			v = v<<8 | uint32(c)
+		}
+		return int32(v), offset, nil
+	case 10:
+		return append([]byte(nil), b...), offset, nil
+	case 15:
+		if size != 4 {
+			return nil, 0, errMMDBInvalid
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), offset, nil
+	}
+	return nil, 0, fmt.Errorf("unsupported MaxMind DB data type %d", kind)
+}
+
+func mmdbUint(v interface{}) uint {
+	switch n := v.(type) {
+	case uint64:
+		return uint(n)
+	case int32:
+		return uint(n)
+	}
+	return 0
+}
+
+// mmdbPath walks nested maps and arrays, e.g. mmdbPath(rec, "city", "names", "en").
+func mmdbPath(v interface{}, path ...interface{}) interface{} {
+	for _, key := range path {
+		switch k := key.(type) {
+		case string:
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			v = m[k]
+		case int:
+			a, ok := v.([]interface{})
+			if !ok || k >= len(a) {
+				return nil
+			}
+			v = a[k]
+		}
+	}
+	return v
+}
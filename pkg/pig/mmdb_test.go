@@ -0,0 +1,258 @@
+package pig
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// mmdbEncode writes v in the MaxMind DB data section format. It covers the
+// types the fixtures need: maps, arrays, strings, unsigned integers,
+// doubles and booleans.
+func mmdbEncode(v interface{}) []byte {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b := mmdbControl(7, len(v))
+		for _, k := range keys {
+			b = append(b, mmdbEncode(k)...)
+			b = append(b, mmdbEncode(v[k])...)
+		}
+		return b
+	case []interface{}:
+		b := mmdbControl(11, len(v))
+		for _, e := range v {
+			b = append(b, mmdbEncode(e)...)
+		}
+		return b
+	case string:
+		return append(mmdbControl(2, len(v)), v...)
+	case uint64:
+		var b []byte
+		for n := v; n > 0; n >>= 8 {
+			b = append([]byte{byte(n)}, b...)
+		}
+		return append(mmdbControl(9, len(b)), b...)
+	case float64:
+		return binary.BigEndian.AppendUint64(mmdbControl(3, 8), math.Float64bits(v))
+	case bool:
+		if v {
+			return mmdbControl(14, 1)
+		}
+		return mmdbControl(14, 0)
+	}
+	panic("mmdbEncode: unsupported type")
+}
+
+// mmdbControl writes a control byte, and the extended type and size bytes
+// when needed. Sizes up to 284 are supported.
+func mmdbControl(kind, size int) []byte {
+	var extra []byte
+	if size >= 29 {
+		extra = []byte{byte(size - 29)}
+		size = 29
+	}
+	b := []byte{byte(kind<<5 | size)}
+	if kind > 7 {
+		b = []byte{byte(size), byte(kind - 7)}
+	}
+	return append(b, extra...)
+}
+
+func TestMMDBDecode(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want interface{}
+	}{
+		{"string", []byte{0x42, 'e', 'n'}, "en"},
+		{"empty string", []byte{0x40}, ""},
+		{"double", append([]byte{0x68}, 0x40, 0x4a, 0x80, 0, 0, 0, 0, 0), 53.0},
+		{"uint16", []byte{0xa2, 0x01, 0xbb}, uint64(443)},
+		{"uint32", []byte{0xc3, 0x01, 0x00, 0x00}, uint64(65536)},
+		{"uint64", []byte{0x01, 0x02, 0x2a}, uint64(42)},
+		{"int32", []byte{0x04, 0x01, 0xff, 0xff, 0xff, 0xfe}, int32(-2)},
+		{"true", []byte{0x01, 0x07}, true},
+		{"false", []byte{0x00, 0x07}, false},
+		{"array", []byte{0x02, 0x04, 0x41, 'a', 0x41, 'b'}, []interface{}{"a", "b"}},
+		{"map", []byte{0xe1, 0x42, 'e', 'n', 0x43, 'P', 'i', 'g'}, map[string]interface{}{"en": "Pig"}},
+		{"nested", mmdbEncode(map[string]interface{}{"city": map[string]interface{}{"names": map[string]interface{}{"en": "Oslo"}}}),
+			map[string]interface{}{"city": map[string]interface{}{"names": map[string]interface{}{"en": "Oslo"}}}},
+		{"long string", append([]byte{0x5d, 0x01}, []byte("abcdefghijklmnopqrstuvwxyz0123")...), "abcdefghijklmnopqrstuvwxyz0123"},
+	}
+	for _, tt := range tests {
+		got, next, err := mmdbDecode(tt.data, 0)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %#v, want %#v", tt.name, got, tt.want)
+		}
+		if next != uint(len(tt.data)) {
+			t.Errorf("%s: next offset %d, want %d", tt.name, next, len(tt.data))
+		}
+	}
+}
+
+func TestMMDBDecodePointer(t *testing.T) {
+	// A map whose value is a pointer to the string at offset 0. The offset
+	// after the pointer is the one following it, not the string's.
+	data := []byte{0x42, 'N', 'O', 0xe1, 0x47, 'c', 'o', 'u', 'n', 't', 'r', 'y', 0x20, 0x00}
+	got, next, err := mmdbDecode(data, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]interface{}{"country": "NO"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+	if next != uint(len(data)) {
+		t.Errorf("next offset %d, want %d", next, len(data))
+	}
+}
+
+func TestMMDBDecodeErrors(t *testing.T) {
+	nested := []byte{}
+	for i := 0; i < 40; i++ {
+		nested = append(nested, 0x01, 0x04)
+	}
+	nested = append(nested, 0x40)
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"truncated string", []byte{0x45, 'a', 'b'}},
+		{"truncated map", []byte{0xe1, 0x41, 'a'}},
+		{"truncated pointer", []byte{0x28, 0x00}},
+		{"pointer out of range", []byte{0x20, 0x40}},
+		{"pointer loop", []byte{0x20, 0x00}},
+		{"pointer cycle", []byte{0x20, 0x02, 0x20, 0x00}},
+		{"nesting too deep", nested},
+		{"map larger than the data", []byte{0xff, 0xff, 0xff, 0xff, 0x40, 0x40}},
+		{"array larger than the data", []byte{0x1f, 0x04, 0xff, 0xff, 0xff, 0x40}},
+		{"bad double", []byte{0x64, 0, 0, 0, 0}},
+		{"unknown type", []byte{0x00, 0x0a}},
+	}
+	for _, tt := range tests {
+		if v, _, err := mmdbDecode(tt.data, 0); err == nil {
+			t.Errorf("%s: got %#v, want an error", tt.name, v)
+		}
+	}
+	if _, _, err := mmdbDecode([]byte{0x20, 0x00}, 0); !errors.Is(err, errMMDBInvalid) {
+		t.Errorf("pointer loop: got %v, want %v", err, errMMDBInvalid)
+	}
+}
+
+// writeTestMMDB writes an IPv4 database with 24-bit records and a single
+// node: addresses in 0.0.0.0/1 map to rec and the rest are not found.
+func writeTestMMDB(t *testing.T, rec map[string]interface{}) string {
+	const nodeCount = 1
+	var buf []byte
+	left := nodeCount + 16
+	buf = append(buf, byte(left>>16), byte(left>>8), byte(left))
+	buf = append(buf, 0, 0, nodeCount)
+	buf = append(buf, make([]byte, 16)...)
+	buf = append(buf, mmdbEncode(rec)...)
+	buf = append(buf, mmdbMetadataMarker...)
+	buf = append(buf, mmdbEncode(map[string]interface{}{
+		"node_count":    uint64(nodeCount),
+		"record_size":   uint64(24),
+		"ip_version":    uint64(4),
+		"database_type": "Test-City",
+	})...)
+
+	path := filepath.Join(t.TempDir(), "test.mmdb")
+	if err := os.WriteFile(path, buf, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestMMDBProvider(t *testing.T) {
+	path := writeTestMMDB(t, map[string]interface{}{
+		"city":         map[string]interface{}{"names": map[string]interface{}{"en": "Oslo"}},
+		"country":      map[string]interface{}{"iso_code": "NO"},
+		"subdivisions": []interface{}{map[string]interface{}{"names": map[string]interface{}{"en": "Oslo County"}}},
+		"location": map[string]interface{}{
+			"latitude":  59.9,
+			"longitude": 10.75,
+			"time_zone": "Europe/Oslo",
+		},
+		"in_eu": false,
+	})
+	p, err := NewMMDBProvider([]string{path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		ip      string
+		want    *Geolocation
+		wantErr bool
+	}{
+		{"10.0.0.1", &Geolocation{City: "Oslo", Region: "Oslo County", Country: "NO", Latitude: 59.9, Longitude: 10.75, TimeZone: "Europe/Oslo"}, false},
+		{"127.255.255.255", &Geolocation{City: "Oslo", Region: "Oslo County", Country: "NO", Latitude: 59.9, Longitude: 10.75, TimeZone: "Europe/Oslo"}, false},
+		{"192.0.2.1", nil, false},
+		{"2001:db8::1", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := p.Locate(net.ParseIP(tt.ip))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Locate(%s) error = %v, want error %v", tt.ip, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Locate(%s) = %+v, want %+v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestMMDBProviderASN(t *testing.T) {
+	tests := []struct {
+		rec  map[string]interface{}
+		want string
+	}{
+		{map[string]interface{}{"autonomous_system_number": uint64(64500), "autonomous_system_organization": "Example Net"}, "AS64500 Example Net"},
+		{map[string]interface{}{"autonomous_system_number": uint64(64500)}, "AS64500"},
+		{map[string]interface{}{"autonomous_system_number": uint64(64500), "autonomous_system_organization": ""}, "AS64500"},
+	}
+	for _, tt := range tests {
+		p, err := NewMMDBProvider([]string{writeTestMMDB(t, tt.rec)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		geo, err := p.Locate(net.ParseIP("10.0.0.1"))
+		if err != nil || geo == nil || geo.Org != tt.want {
+			t.Errorf("Locate with %v = %+v, %v, want org %q", tt.rec, geo, err, tt.want)
+		}
+	}
+}
+
+func TestOpenMMDBErrors(t *testing.T) {
+	dir := t.TempDir()
+	noMarker := filepath.Join(dir, "empty.mmdb")
+	if err := os.WriteFile(noMarker, []byte("not a database"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	badSize := filepath.Join(dir, "size.mmdb")
+	meta := mmdbEncode(map[string]interface{}{"node_count": uint64(1), "record_size": uint64(20), "ip_version": uint64(4)})
+	if err := os.WriteFile(badSize, append(append(make([]byte, 22), mmdbMetadataMarker...), meta...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{noMarker, badSize, filepath.Join(dir, "missing.mmdb")} {
+		if _, err := openMMDB(path); err == nil {
+			t.Errorf("openMMDB(%s) returned no error", filepath.Base(path))
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package pig
+
+import (
+	"net"
+	"strconv"
+	"strings"
+)
+
+// reverseIP returns the labels used under in-addr.arpa, ip6.arpa and DNSBL
+// zones: reversed octets for IPv4 and reversed nibbles for IPv6.
+func reverseIP(ip string) string {
+	addr := net.ParseIP(ip)
+	if addr != nil && addr.To4() == nil {
+		nibbles := make([]string, 0, 32)
+		for i := len(addr) - 1; i >= 0; i-- {
+			nibbles = append(nibbles, strconv.FormatUint(uint64(addr[i]&0x0f), 16), strconv.FormatUint(uint64(addr[i]>>4), 16))
+		}
+		return strings.Join(nibbles, ".")
+	}
+
+	octets := strings.Split(ip, ".")
+	reversed := []string{}
+
+	for i := len(octets) - 1; i >= 0; i-- {
+		reversed = append(reversed, octets[i])
+	}
+
+	return strings.Join(reversed, ".")
+}
+
+func arpaName(ip net.IP) string {
+	if ip.To4() != nil {
+		return reverseIP(ip.To4().String()) + ".in-addr.arpa."
+	}
+	return reverseIP(ip.String()) + ".ip6.arpa."
+}
+
+// ValidDomain reports whether domain, with or without a trailing dot, is a
+// hostname: letters, digits, hyphens and underscores in labels of 1 to 63
+// characters, 253 at most in all.
+func ValidDomain(domain string) bool {
+	name := strings.TrimSuffix(domain, ".")
+	if name == "" || len(name) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,248 @@
+package pig
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type PassiveDNSRecord struct {
+	RRName    string    `json:"rrname"`
+	RRType    string    `json:"rrtype"`
+	RData     string    `json:"rdata"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+type pdnsIndex struct {
+	byName  map[string][]PassiveDNSRecord
+	byRData map[string][]PassiveDNSRecord
+}
+
+var pdnsTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+func loadPassiveDNS(paths []string) (*pdnsIndex, error) {
+	index := &pdnsIndex{byName: map[string][]PassiveDNSRecord{}, byRData: map[string][]PassiveDNSRecord{}}
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		var records []PassiveDNSRecord
+		if strings.HasSuffix(path, ".csv") {
+			records, err = readPassiveDNSCSV(f)
+		} else {
+			records, err = readPassiveDNSJSONL(f)
+		}
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for _, rec := range records {
+			index.byName[rec.RRName] = append(index.byName[rec.RRName], rec)
+			index.byRData[rec.RData] = append(index.byRData[rec.RData], rec)
+		}
+	}
+	return index, nil
+}
+
+func readPassiveDNSCSV(r io.Reader) ([]PassiveDNSRecord, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"rrname", "rrtype", "rdata"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing %s column", name)
+		}
+	}
+	field := func(row []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	records := []PassiveDNSRecord{}
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		rec, err := newPassiveDNSRecord(field(row, "rrname"), field(row, "rrtype"), field(row, "rdata"),
+			field(row, "first_seen"), field(row, "last_seen"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		records = append(records, rec)
+	}
+}
+
+func readPassiveDNSJSONL(r io.Reader) ([]PassiveDNSRecord, error) {
+	records := []PassiveDNSRecord{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var entry struct {
+			RRName    string          `json:"rrname"`
+			RRType    string          `json:"rrtype"`
+			RData     json.RawMessage `json:"rdata"`
+			FirstSeen json.RawMessage `json:"first_seen"`
+			LastSeen  json.RawMessage `json:"last_seen"`
+		}
+		if err := json.Unmarshal([]byte(text), &entry); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		// rdata may be a single string or a list of strings.
+		rdatas := []string{}
+		var single string
+		if err := json.Unmarshal(entry.RData, &single); err == nil {
+			rdatas = append(rdatas, single)
+		} else if err := json.Unmarshal(entry.RData, &rdatas); err != nil {
+			return nil, fmt.Errorf("line %d: rdata: %w", line, err)
+		}
+		for _, rdata := range rdatas {
+			rec, err := newPassiveDNSRecord(entry.RRName, entry.RRType, rdata,
+				jsonScalar(entry.FirstSeen), jsonScalar(entry.LastSeen))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			records = append(records, rec)
+		}
+	}
+	return records, scanner.Err()
+}
+
+func jsonScalar(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return strings.TrimSpace(string(raw))
+}
+
+func newPassiveDNSRecord(rrname, rrtype, rdata, firstSeen, lastSeen string) (PassiveDNSRecord, error) {
+	rec := PassiveDNSRecord{
+		RRName: strings.ToLower(strings.TrimSuffix(rrname, ".")),
+		RRType: strings.ToUpper(rrtype),
+		RData:  strings.ToLower(strings.TrimSuffix(rdata, ".")),
+	}
+	if rec.RRName == "" || rec.RRType == "" || rec.RData == "" {
+		return rec, fmt.Errorf("rrname, rrtype and rdata are required")
+	}
+	if ip := net.ParseIP(rec.RData); ip != nil {
+		rec.RData = ip.String()
+	}
+	var err error
+	if rec.FirstSeen, err = parsePassiveDNSTime(firstSeen); err != nil {
+		return rec, fmt.Errorf("first_seen: %w", err)
+	}
+	if rec.LastSeen, err = parsePassiveDNSTime(lastSeen); err != nil {
+		return rec, fmt.Errorf("last_seen: %w", err)
+	}
+	return rec, nil
+}
+
+func parsePassiveDNSTime(value string) (time.Time, error) {
+	if value == "" || value == "null" {
+		return time.Time{}, nil
+	}
+	if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(secs, 0).UTC(), nil
+	}
+	for _, layout := range pdnsTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time %q", value)
+}
+
+func (rec PassiveDNSRecord) Seen() string {
+	format := func(t time.Time) string {
+		if t.IsZero() {
+			return "?"
+		}
+		return t.Format("2006-01-02")
+	}
+	return format(rec.FirstSeen) + " to " + format(rec.LastSeen)
+}
+
+func sortPassiveDNS(records []PassiveDNSRecord) {
+	sort.Slice(records, func(i, j int) bool {
+		if !records[i].LastSeen.Equal(records[j].LastSeen) {
+			return records[i].LastSeen.After(records[j].LastSeen)
+		}
+		return records[i].RRName+records[i].RData < records[j].RRName+records[j].RData
+	})
+}
+
+// address reports whether rec maps a name to an address. Other types,
+// such as a TXT record holding an IP, are neither history nor neighbors.
+func (rec PassiveDNSRecord) address() bool {
+	return rec.RRType == "A" || rec.RRType == "AAAA"
+}
+
+type PassiveDNSReport struct {
+	History   []PassiveDNSRecord            `json:"history"`
+	Neighbors map[string][]PassiveDNSRecord `json:"neighbors"`
+}
+
+// PassiveDNS returns the historical addresses of domain and the other names
+// seen on each of ips in the passive DNS files. It returns nil when the
+// scanner has no passive DNS data.
+func (s *Scanner) PassiveDNS(domain string, ips []net.IP) *PassiveDNSReport {
+	index := s.passive
+	if index == nil {
+		return nil
+	}
+	name := strings.ToLower(strings.TrimSuffix(domain, "."))
+
+	report := &PassiveDNSReport{History: []PassiveDNSRecord{}, Neighbors: map[string][]PassiveDNSRecord{}}
+	for _, rec := range index.byName[name] {
+		if rec.address() {
+			report.History = append(report.History, rec)
+		}
+	}
+	sortPassiveDNS(report.History)
+
+	for _, ip := range ips {
+		neighbors := []PassiveDNSRecord{}
+		for _, rec := range index.byRData[ip.String()] {
+			if rec.address() && rec.RRName != name {
+				neighbors = append(neighbors, rec)
+			}
+		}
+		sortPassiveDNS(neighbors)
+		report.Neighbors[ip.String()] = neighbors
+	}
+	return report
+}
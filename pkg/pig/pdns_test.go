@@ -0,0 +1,54 @@
+package pig
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testPassiveDNS = `{"rrname": "example.com.", "rrtype": "A", "rdata": "192.0.2.1", "first_seen": "2023-01-01", "last_seen": "2024-01-01"}
+{"rrname": "example.com", "rrtype": "A", "rdata": "192.0.2.0", "first_seen": "2020-01-01", "last_seen": "2021-01-01"}
+{"rrname": "example.com", "rrtype": "TXT", "rdata": "192.0.2.1", "first_seen": "2023-01-01", "last_seen": "2024-01-01"}
+{"rrname": "shop.example.net", "rrtype": "A", "rdata": "192.0.2.1", "first_seen": "2022-01-01", "last_seen": "2023-06-01"}
+{"rrname": "mail.example.org", "rrtype": "TXT", "rdata": "192.0.2.1", "first_seen": "2022-01-01", "last_seen": "2023-06-01"}
+{"rrname": "spf.example.org", "rrtype": "SPF", "rdata": "192.0.2.1", "first_seen": "2022-01-01", "last_seen": "2023-06-01"}
+{"rrname": "v6.example.net", "rrtype": "AAAA", "rdata": "2001:DB8::0001", "first_seen": "2022-01-01", "last_seen": "2023-06-01"}
+`
+
+func TestPassiveDNS(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pdns.jsonl")
+	if err := os.WriteFile(path, []byte(testPassiveDNS), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewScanner(Options{PassiveDNSFiles: []string{path}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := s.PassiveDNS("example.com.", []net.IP{net.ParseIP("192.0.2.1"), net.ParseIP("2001:db8::1")})
+
+	history := []string{}
+	for _, rec := range report.History {
+		history = append(history, rec.RRType+" "+rec.RData)
+	}
+	if !equalStrings(history, []string{"A 192.0.2.1", "A 192.0.2.0"}) {
+		t.Errorf("history %v, want the two A records, newest first", history)
+	}
+
+	for ip, want := range map[string][]string{
+		"192.0.2.1":   {"shop.example.net A"},
+		"2001:db8::1": {"v6.example.net AAAA"},
+	} {
+		got := []string{}
+		for _, rec := range report.Neighbors[ip] {
+			got = append(got, rec.RRName+" "+rec.RRType)
+		}
+		if !equalStrings(got, want) {
+			t.Errorf("neighbors of %s = %v, want %v", ip, got, want)
+		}
+	}
+
+	if report := newTestScanner(t, newTestServer(t, false), Options{}).PassiveDNS("example.com", nil); report != nil {
+		t.Errorf("PassiveDNS without files = %+v, want nil", report)
+	}
+}
@@ -0,0 +1,66 @@
+package pig
+
+import "sync"
+
+// Profile limits the traffic a scan sends.
+type Profile string
+
+const (
+	// Passive only sends queries to the recursive resolver.
+	Passive Profile = "passive"
+	// Standard also asks the nameservers of CNAMEs for conflicting data,
+	// and fetches CNAME targets over HTTP when ProbeHTTP is set.
+	Standard Profile = "standard"
+	// Active also sends zone transfer, TCP and DNS over TLS probes straight
+	// to the domain's nameservers, and amplification queries for the
+	// domain's largest record types.
+	Active Profile = "active"
+)
+
+var activeSections = []string{"zonetransfer", "amplification", "axfr"}
+
+// IsActiveSection reports whether a report section only runs under the
+// Active profile.
+func IsActiveSection(section string) bool {
+	for _, s := range activeSections {
+		if s == section {
+			return true
+		}
+	}
+	return false
+}
+
+// A Probe counts the requests of one kind sent to a target other than the
+// recursive resolver, or that load third-party servers.
+type Probe struct {
+	Kind   string `json:"kind"`
+	Target string `json:"target"`
+	Count  int    `json:"count"`
+}
+
+// probeLog collects the probes of one report. A nil log records nothing.
+type probeLog struct {
+	mu     sync.Mutex
+	probes []Probe
+}
+
+func (l *probeLog) add(kind, target string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i := range l.probes {
+		if l.probes[i].Kind == kind && l.probes[i].Target == target {
+			l.probes[i].Count++
+			return
+		}
+	}
+	l.probes = append(l.probes, Probe{Kind: kind, Target: target, Count: 1})
+}
+
+func (l *probeLog) list() []Probe {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Probe{}, l.probes...)
+}
@@ -0,0 +1,346 @@
+package pig
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Record struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	TTL  uint32 `json:"ttl"`
+	Data string `json:"data"`
+}
+
+type AddressInfo struct {
+	IP         net.IP             `json:"ip"`
+	Geo        *Geolocation       `json:"geo,omitempty"`
+	GeoError   string             `json:"geo_error,omitempty"`
+	ASN        *ASNInfo           `json:"asn,omitempty"`
+	ASNError   string             `json:"asn_error,omitempty"`
+	Blocklists []*BlocklistResult `json:"blocklists,omitempty"`
+	Reverse    *FCrDNSResult      `json:"reverse,omitempty"`
+}
+
+type MXInfo struct {
+	Host       string             `json:"host"`
+	Pref       uint16             `json:"pref"`
+	Service    string             `json:"service"`
+	Addresses  []net.IP           `json:"addresses,omitempty"`
+	Reverse    []FCrDNSResult     `json:"reverse,omitempty"`
+	Blocklists []*BlocklistResult `json:"blocklists,omitempty"`
+}
+
+type NSInfo struct {
+	Host      string   `json:"host"`
+	Service   string   `json:"service"`
+	Addresses []net.IP `json:"addresses,omitempty"`
+}
+
+type SRVInfo struct {
+	Target   string `json:"target"`
+	Port     uint16 `json:"port"`
+	Priority uint16 `json:"priority"`
+	Weight   uint16 `json:"weight"`
+}
+
+// Report holds everything pig learns about a domain in one run. It is what
+// gets printed, saved as a snapshot and compared by pig diff.
+type Report struct {
+	Domain           string                 `json:"domain"`
+	Time             time.Time              `json:"time"`
+	Sections         []string               `json:"sections"`
+	Profile          Profile                `json:"profile,omitempty"`
+	Probes           []Probe                `json:"probes"`
+	Lookups          []Lookup               `json:"lookups,omitempty"`
+	Records          []Record               `json:"records"`
+	Addresses        []*AddressInfo         `json:"addresses,omitempty"`
+	CNAME            *CNAMEChain            `json:"cname,omitempty"`
+	Takeover         *TakeoverResult        `json:"takeover,omitempty"`
+	MX               []MXInfo               `json:"mx,omitempty"`
+	NS               []NSInfo               `json:"ns,omitempty"`
+	Transports       *TransportReport       `json:"transports,omitempty"`
+	DNSSEC           *DNSSECStatus          `json:"dnssec,omitempty"`
+	Passive          *PassiveDNSReport      `json:"passive_dns,omitempty"`
+	SPF              []string               `json:"spf,omitempty"`
+	SPFLookups       int                    `json:"spf_lookups,omitempty"`
+	DMARC            []string               `json:"dmarc,omitempty"`
+	SRV              []SRVInfo              `json:"srv,omitempty"`
+	TXT              []string               `json:"txt,omitempty"`
+	DomainBlocklists *DomainBlocklistReport `json:"domain_blocklists,omitempty"`
+	Networks         []*NetworkGroup        `json:"networks,omitempty"`
+	Vendors          []Vendor               `json:"vendors,omitempty"`
+	ZoneTransfer     []ZoneTransferCheck    `json:"zone_transfer,omitempty"`
+	Amplification    []AmplificationCheck   `json:"amplification,omitempty"`
+	AXFR             []AXFRCheck            `json:"axfr,omitempty"`
+	Score            *Scorecard             `json:"score,omitempty"`
+}
+
+// Sections are the parts of a report that can be selected, in the order
+// they are printed. Records, and the MX and NS hosts with their addresses,
+// are always looked up since the other sections build on them.
+var Sections = []string{
+	"addresses", "cname", "mx", "ns", "transports", "dnssec", "reverse", "passive", "spf", "srv", "txt",
+	"blocklists", "networks", "vendors", "zonetransfer", "amplification", "axfr",
+}
+
+// Has reports whether section was selected when r was built. Snapshots
+// saved before sections were recorded have every section.
+func (r *Report) Has(section string) bool {
+	if r.Sections == nil {
+		return true
+	}
+	for _, s := range r.Sections {
+		if s == section {
+			return true
+		}
+	}
+	return false
+}
+
+var reportTypes = []uint16{typeA, typeAAAA, typeCNAME, typeMX, typeNS, typeSOA, typeTXT, typeSRV, typeCAA}
+
+// Records asks the resolver for each record type at domain. A and AAAA
+// answers are kept even when they are owned by the end of a CNAME chain.
+func (s *Scanner) Records(domain string) []Record {
+	records := []Record{}
+	for _, t := range reportTypes {
+		msg, err := s.query(domain, t)
+		if err != nil {
+			continue
+		}
+		for _, rr := range msg.Answer {
+			if rr.Type != t || (t != typeA && t != typeAAAA && !strings.EqualFold(rr.Name, fqdn(domain))) {
+				continue
+			}
+			records = append(records, Record{Name: strings.ToLower(rr.Name), Type: typeString(t), TTL: rr.TTL, Data: rr.Data})
+		}
+	}
+	return records
+}
+
+func (r *Report) values(rtype string) []string {
+	values := []string{}
+	for _, rec := range r.Records {
+		if rec.Type == rtype {
+			values = append(values, rec.Data)
+		}
+	}
+	return values
+}
+
+func (r *Report) IPs() []net.IP {
+	ips := []net.IP{}
+	for _, addr := range r.Addresses {
+		ips = append(ips, addr.IP)
+	}
+	return ips
+}
+
+// Address gathers geolocation, ASN, blocklist and reverse DNS data for ip.
+func (s *Scanner) Address(ip net.IP) *AddressInfo {
+	info := &AddressInfo{IP: ip}
+	s.locate(info)
+	info.Blocklists = s.Blocklists(ip)
+	reverse := s.ForwardConfirm(ip)
+	info.Reverse = &reverse
+	return info
+}
+
+func (s *Scanner) locate(info *AddressInfo) {
+	ip := info.IP
+	if geo, err := s.Geolocate(ip); err != nil {
+		info.GeoError = err.Error()
+	} else {
+		info.Geo = geo
+	}
+	if asn, err := s.ASN(ip); err != nil {
+		info.ASNError = err.Error()
+	} else {
+		info.ASN = asn
+	}
+}
+
+// Report runs the given sections against domain, or every section the
+// scanner's profile allows when none are given. Unknown section names, and
+// active sections outside the Active profile, are ignored. The report lists
+// every probe sent to servers other than the resolver, and the outcome of
+// every query sent to it, and ends with the findings of the scanner's rules.
+func (s *Scanner) Report(domain string, sections ...string) *Report {
+	log := &LookupLog{parent: s.log}
+	s = s.WithLog(log)
+
+	report := &Report{Domain: strings.TrimSuffix(strings.ToLower(domain), "."), Time: time.Now().UTC(), Sections: []string{}, Profile: s.profile}
+	want := map[string]bool{}
+	for _, section := range sections {
+		want[section] = true
+	}
+	for _, section := range Sections {
+		if IsActiveSection(section) && s.profile != Active {
+			continue
+		}
+		if len(sections) == 0 || want[section] {
+			report.Sections = append(report.Sections, section)
+		}
+	}
+	probes := &probeLog{}
+	has := report.Has
+	report.Records = s.Records(domain)
+
+	for _, rec := range report.Records {
+		if rec.Type != "A" && rec.Type != "AAAA" {
+			continue
+		}
+		ip := net.ParseIP(rec.Data)
+		if ip == nil {
+			continue
+		}
+		info := &AddressInfo{IP: ip}
+		if has("addresses") {
+			s.locate(info)
+		}
+		if has("blocklists") {
+			info.Blocklists = s.Blocklists(ip)
+		}
+		if has("reverse") {
+			reverse := s.ForwardConfirm(ip)
+			info.Reverse = &reverse
+		}
+		report.Addresses = append(report.Addresses, info)
+	}
+
+	if has("cname") {
+		if chain, _ := s.cnameChain(domain, probes); chain != nil && len(chain.Hops) > 0 {
+			report.CNAME = chain
+			report.Takeover = s.takeover(chain, probes)
+		}
+	}
+
+	for _, data := range report.values("MX") {
+		pref, host, ok := strings.Cut(data, " ")
+		if !ok {
+			continue
+		}
+		n, _ := strconv.ParseUint(pref, 10, 16)
+		mx := MXInfo{Host: host, Pref: uint16(n), Service: s.DetectService(host)}
+		mx.Addresses = s.lookupIP(host)
+		if has("reverse") {
+			for _, ip := range mx.Addresses {
+				mx.Reverse = append(mx.Reverse, s.ForwardConfirm(ip))
+			}
+		}
+		if has("blocklists") {
+			for _, ip := range mx.Addresses {
+				mx.Blocklists = append(mx.Blocklists, s.Blocklists(ip)...)
+			}
+		}
+		report.MX = append(report.MX, mx)
+	}
+	for _, host := range report.values("NS") {
+		ns := NSInfo{Host: host, Service: s.DetectService(host)}
+		ns.Addresses = s.lookupIP(host)
+		report.NS = append(report.NS, ns)
+	}
+	if has("transports") {
+		report.Transports = s.transports(domain, report.NS, probes)
+	}
+	if has("dnssec") {
+		report.DNSSEC = s.DNSSEC(domain)
+	}
+	if has("passive") {
+		report.Passive = s.PassiveDNS(domain, report.IPs())
+	}
+
+	report.TXT = report.values("TXT")
+	for _, txt := range report.TXT {
+		if strings.HasPrefix(txt, "v=spf1") {
+			report.SPF = append(report.SPF, txt)
+		}
+	}
+	if has("spf") && len(report.SPF) == 1 {
+		report.SPFLookups = s.spfLookups(report.SPF[0])
+	}
+	if has("spf") {
+		for _, txt := range s.lookupTXT("_dmarc." + report.Domain) {
+			if strings.HasPrefix(txt, "v=DMARC1") {
+				report.DMARC = append(report.DMARC, txt)
+			}
+		}
+	}
+	for _, data := range report.values("SRV") {
+		fields := strings.Fields(data)
+		if len(fields) != 4 {
+			continue
+		}
+		priority, _ := strconv.ParseUint(fields[0], 10, 16)
+		weight, _ := strconv.ParseUint(fields[1], 10, 16)
+		port, _ := strconv.ParseUint(fields[2], 10, 16)
+		report.SRV = append(report.SRV, SRVInfo{Target: fields[3], Port: uint16(port), Priority: uint16(priority), Weight: uint16(weight)})
+	}
+
+	targets := []string{report.Domain}
+	addresses := map[string][]net.IP{"web": report.IPs()}
+	for _, mx := range report.MX {
+		targets = append(targets, strings.TrimSuffix(mx.Host, "."))
+		addresses["mail"] = append(addresses["mail"], mx.Addresses...)
+	}
+	for _, ns := range report.NS {
+		targets = append(targets, strings.TrimSuffix(ns.Host, "."))
+		addresses["dns"] = append(addresses["dns"], ns.Addresses...)
+	}
+	if has("blocklists") {
+		report.DomainBlocklists = s.DomainBlocklists(targets)
+	}
+	if has("networks") {
+		report.Networks = s.Networks(addresses)
+	}
+	if has("vendors") {
+		report.Vendors = s.Vendors(report)
+	}
+
+	if has("zonetransfer") {
+		report.ZoneTransfer = s.zoneTransfer(domain, report.Nameservers(), probes)
+	}
+	if has("amplification") {
+		report.Amplification = s.amplification(domain, probes)
+	}
+	if has("axfr") {
+		report.AXFR = s.axfr(domain, report.Nameservers(), probes)
+	}
+	report.Probes = probes.list()
+	report.Lookups = log.Lookups()
+	report.Score = s.Score(report)
+	return report
+}
+
+func (r *Report) Nameservers() []string {
+	hosts := []string{}
+	for _, ns := range r.NS {
+		hosts = append(hosts, ns.Host)
+	}
+	return hosts
+}
+
+func (r *Report) signals() *signals {
+	sig := &signals{asns: make(map[string]string), txt: r.TXT, spf: spfIncludes(r.SPF)}
+	if r.CNAME != nil {
+		for _, hop := range r.CNAME.Hops {
+			sig.cnames = append(sig.cnames, hop.Target)
+		}
+	}
+	for _, addr := range r.Addresses {
+		sig.ips = append(sig.ips, addr.IP)
+		if addr.ASN != nil {
+			sig.asns[addr.ASN.ASN] = addr.IP.String()
+		}
+	}
+	for _, ns := range r.NS {
+		sig.ns = append(sig.ns, ns.Host)
+	}
+	for _, mx := range r.MX {
+		sig.mx = append(sig.mx, mx.Host)
+	}
+	return sig
+}
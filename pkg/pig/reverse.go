@@ -0,0 +1,33 @@
+package pig
+
+import "net"
+
+type FCrDNSResult struct {
+	IP        net.IP   `json:"ip"`
+	PTRs      []string `json:"ptrs,omitempty"`
+	Confirmed []string `json:"confirmed,omitempty"`
+}
+
+func (r FCrDNSResult) Pass() bool {
+	return len(r.Confirmed) > 0
+}
+
+// ForwardConfirm looks up the PTR names of ip and resolves each one back,
+// keeping the names whose A/AAAA records include ip.
+func (s *Scanner) ForwardConfirm(ip net.IP) FCrDNSResult {
+	res := FCrDNSResult{IP: ip}
+	msg, err := s.query(arpaName(ip), typePTR)
+	if err != nil {
+		return res
+	}
+	for _, ptr := range msg.answers(arpaName(ip), typePTR) {
+		res.PTRs = append(res.PTRs, ptr.Data)
+		for _, addr := range s.lookupIP(ptr.Data) {
+			if addr.Equal(ip) {
+				res.Confirmed = append(res.Confirmed, ptr.Data)
+				break
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,621 @@
+package pig
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed rules.json
+var rulesJSON []byte
+
+const rulesVersion = 1
+
+// Severity ranks a finding.
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityLow      Severity = "low"
+	SeverityMedium   Severity = "medium"
+	SeverityHigh     Severity = "high"
+	SeverityCritical Severity = "critical"
+)
+
+// severityPenalty is how many points a finding takes off the score of its
+// category, out of 100.
+var severityPenalty = map[Severity]int{
+	SeverityInfo:     0,
+	SeverityLow:      5,
+	SeverityMedium:   10,
+	SeverityHigh:     20,
+	SeverityCritical: 40,
+}
+
+// ParseSeverity returns the severity named s.
+func ParseSeverity(s string) (Severity, error) {
+	severity := Severity(strings.ToLower(s))
+	if _, ok := severityPenalty[severity]; !ok {
+		return "", fmt.Errorf("unknown severity %q: must be info, low, medium, high or critical", s)
+	}
+	return severity, nil
+}
+
+// AtLeast reports whether s is as severe as min or more.
+func (s Severity) AtLeast(min Severity) bool {
+	return severityPenalty[s] >= severityPenalty[min]
+}
+
+// Categories are the parts of a domain that get a grade, in the order
+// they are listed.
+var Categories = []string{"dns", "mail", "web"}
+
+// A Rule raises a finding when all of its conditions hold. Rules whose
+// facts were not collected, because their section was not run or the
+// lookup behind them failed, are skipped.
+type Rule struct {
+	ID          string      `json:"id"`
+	Category    string      `json:"category"`
+	Severity    Severity    `json:"severity"`
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	Remediation string      `json:"remediation,omitempty"`
+	When        []Condition `json:"when"`
+	Disabled    bool        `json:"disabled,omitempty"`
+}
+
+// A Condition compares a fact about the report with a value. The ops are
+// eq, ne, lt, le, gt and ge, in (the value is a list), contains (the fact
+// is a list), and empty and nonempty, which take no value.
+type Condition struct {
+	Fact  string      `json:"fact"`
+	Op    string      `json:"op"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+type rulesConfig struct {
+	Version int    `json:"version"`
+	Rules   []Rule `json:"rules"`
+}
+
+// factKinds lists the facts conditions can test and what kind of value
+// each one holds.
+var factKinds = map[string]string{
+	"records.count":        "number",
+	"mx.count":             "number",
+	"ns.count":             "number",
+	"ns.providers":         "number",
+	"caa.count":            "number",
+	"spf.count":            "number",
+	"spf.all":              "string",
+	"spf.errors":           "list",
+	"spf.lookups":          "number",
+	"dmarc.count":          "number",
+	"dmarc.policy":         "string",
+	"dmarc.errors":         "list",
+	"caa.errors":           "list",
+	"dnssec.status":        "string",
+	"cname.dangling":       "bool",
+	"cname.loop":           "bool",
+	"cname.conflicts":      "list",
+	"cname.takeover_risk":  "string",
+	"web.blocklisted":      "list",
+	"mx.blocklisted":       "list",
+	"domain.blocklisted":   "list",
+	"mx.fcrdns_failed":     "list",
+	"zone.transfer_open":   "list",
+	"amplification.factor": "number",
+}
+
+// Facts returns the names of the facts rule conditions can test.
+func Facts() []string {
+	names := []string{}
+	for name := range factKinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// loadRules reads the embedded rules followed by each override file. An
+// override rule with the same ID replaces the earlier one, and
+// "disabled": true removes it.
+func loadRules(paths []string) ([]Rule, error) {
+	rules, err := parseRules(rulesJSON)
+	if err != nil {
+		return nil, fmt.Errorf("embedded rules.json: %w", err)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		override, err := parseRules(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for _, rule := range override {
+			replaced := false
+			for i := range rules {
+				if rules[i].ID == rule.ID {
+					rules[i] = rule
+					replaced = true
+				}
+			}
+			if !replaced {
+				rules = append(rules, rule)
+			}
+		}
+	}
+
+	enabled := rules[:0]
+	for _, rule := range rules {
+		if !rule.Disabled {
+			enabled = append(enabled, rule)
+		}
+	}
+	return enabled, nil
+}
+
+func parseRules(data []byte) ([]Rule, error) {
+	var config rulesConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	if config.Version > rulesVersion {
+		return nil, fmt.Errorf("unsupported rules version %d", config.Version)
+	}
+	for i := range config.Rules {
+		rule := &config.Rules[i]
+		if rule.ID == "" {
+			return nil, fmt.Errorf("rule %d has no id", i)
+		}
+		if rule.Disabled {
+			continue
+		}
+		if !isCategory(rule.Category) {
+			return nil, fmt.Errorf("rule %s: unknown category %q, expected one of: %s", rule.ID, rule.Category, strings.Join(Categories, ", "))
+		}
+		if _, ok := severityPenalty[rule.Severity]; !ok {
+			return nil, fmt.Errorf("rule %s: unknown severity %q", rule.ID, rule.Severity)
+		}
+		if len(rule.When) < 1 {
+			return nil, fmt.Errorf("rule %s has no conditions", rule.ID)
+		}
+		for _, cond := range rule.When {
+			if err := cond.check(); err != nil {
+				return nil, fmt.Errorf("rule %s: %w", rule.ID, err)
+			}
+		}
+	}
+	return config.Rules, nil
+}
+
+func isCategory(category string) bool {
+	for _, c := range Categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Condition) check() error {
+	kind, ok := factKinds[c.Fact]
+	if !ok {
+		return fmt.Errorf("unknown fact %q", c.Fact)
+	}
+	switch c.Op {
+	case "eq", "ne":
+		if c.Value == nil {
+			return fmt.Errorf("%s %s needs a value", c.Fact, c.Op)
+		}
+	case "lt", "le", "gt", "ge":
+		if kind != "number" {
+			return fmt.Errorf("%s is not a number", c.Fact)
+		}
+		if _, ok := c.Value.(float64); !ok {
+			return fmt.Errorf("%s %s needs a number", c.Fact, c.Op)
+		}
+	case "in":
+		if _, ok := c.Value.([]interface{}); !ok {
+			return fmt.Errorf("%s in needs a list", c.Fact)
+		}
+	case "contains", "empty", "nonempty":
+		if kind != "list" {
+			return fmt.Errorf("%s is not a list", c.Fact)
+		}
+	default:
+		return fmt.Errorf("unknown op %q", c.Op)
+	}
+	return nil
+}
+
+// holds reports whether the condition is true of the fact value.
+func (c Condition) holds(value interface{}) bool {
+	switch c.Op {
+	case "eq":
+		return equalFact(value, c.Value)
+	case "ne":
+		return !equalFact(value, c.Value)
+	case "lt", "le", "gt", "ge":
+		n, ok := value.(float64)
+		want, _ := c.Value.(float64)
+		if !ok {
+			return false
+		}
+		switch c.Op {
+		case "lt":
+			return n < want
+		case "le":
+			return n <= want
+		case "gt":
+			return n > want
+		}
+		return n >= want
+	case "in":
+		list, _ := c.Value.([]interface{})
+		for _, v := range list {
+			if equalFact(value, v) {
+				return true
+			}
+		}
+		return false
+	case "contains":
+		list, _ := value.([]string)
+		for _, item := range list {
+			if equalFact(item, c.Value) {
+				return true
+			}
+		}
+		return false
+	case "empty":
+		list, _ := value.([]string)
+		return len(list) == 0
+	case "nonempty":
+		list, _ := value.([]string)
+		return len(list) > 0
+	}
+	return false
+}
+
+// equalFact compares a fact with a value decoded from JSON. Strings are
+// compared without regard to case.
+func equalFact(fact, value interface{}) bool {
+	switch f := fact.(type) {
+	case string:
+		v, ok := value.(string)
+		return ok && strings.EqualFold(f, v)
+	case float64:
+		v, ok := value.(float64)
+		return ok && f == v
+	case bool:
+		v, ok := value.(bool)
+		return ok && f == v
+	}
+	return false
+}
+
+// A Finding is a rule that matched a report.
+type Finding struct {
+	ID          string   `json:"id"`
+	Category    string   `json:"category"`
+	Severity    Severity `json:"severity"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Remediation string   `json:"remediation,omitempty"`
+	Evidence    []string `json:"evidence,omitempty"`
+}
+
+// A Grade scores one category from 100, less a penalty for each finding by
+// severity, and turns the score into a letter from A to F.
+type Grade struct {
+	Category string `json:"category"`
+	Grade    string `json:"grade"`
+	Score    int    `json:"score"`
+	Findings int    `json:"findings"`
+}
+
+// A Scorecard holds the findings of a report, most severe first, and the
+// grades of the categories that had at least one rule evaluated.
+type Scorecard struct {
+	Grades   []Grade   `json:"grades"`
+	Findings []Finding `json:"findings"`
+}
+
+// Rules returns the rules the scanner scores reports with.
+func (s *Scanner) Rules() []Rule {
+	return append([]Rule{}, s.rules...)
+}
+
+// Score runs the scanner's rules against a report, which may be a loaded
+// snapshot. It returns nil when the domain has no records.
+func (s *Scanner) Score(r *Report) *Scorecard {
+	if len(r.Records) < 1 {
+		return nil
+	}
+	facts := r.facts()
+	card := &Scorecard{Grades: []Grade{}, Findings: []Finding{}}
+	evaluated := map[string]bool{}
+	for _, rule := range s.rules {
+		matched, known := true, true
+		evidence := []string{}
+		for _, cond := range rule.When {
+			value, ok := facts[cond.Fact]
+			if !ok {
+				known = false
+				break
+			}
+			if !cond.holds(value) {
+				matched = false
+			}
+			evidence = append(evidence, describeFact(cond.Fact, value)...)
+		}
+		if !known {
+			continue
+		}
+		evaluated[rule.Category] = true
+		if !matched {
+			continue
+		}
+		card.Findings = append(card.Findings, Finding{
+			ID:          rule.ID,
+			Category:    rule.Category,
+			Severity:    rule.Severity,
+			Title:       rule.Title,
+			Description: rule.Description,
+			Remediation: rule.Remediation,
+			Evidence:    evidence,
+		})
+	}
+	sort.SliceStable(card.Findings, func(i, j int) bool {
+		return severityPenalty[card.Findings[i].Severity] > severityPenalty[card.Findings[j].Severity]
+	})
+
+	for _, category := range Categories {
+		if !evaluated[category] {
+			continue
+		}
+		grade := Grade{Category: category, Score: 100}
+		for _, f := range card.Findings {
+			if f.Category == category {
+				grade.Score -= severityPenalty[f.Severity]
+				grade.Findings++
+			}
+		}
+		if grade.Score < 0 {
+			grade.Score = 0
+		}
+		grade.Grade = letterGrade(grade.Score)
+		card.Grades = append(card.Grades, grade)
+	}
+	return card
+}
+
+func letterGrade(score int) string {
+	switch {
+	case score >= 90:
+		return "A"
+	case score >= 80:
+		return "B"
+	case score >= 70:
+		return "C"
+	case score >= 60:
+		return "D"
+	}
+	return "F"
+}
+
+// describeFact renders a fact as evidence: the items of a list, or
+// name = value.
+func describeFact(name string, value interface{}) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case float64:
+		return []string{name + " = " + strconv.FormatFloat(v, 'f', -1, 64)}
+	case string:
+		if v == "" {
+			return []string{name + " is empty"}
+		}
+		return []string{name + " = " + v}
+	}
+	return []string{fmt.Sprintf("%s = %v", name, value)}
+}
+
+// facts collects the values rules test. A fact is left out when its
+// section was not run or the lookup it depends on failed, so the rules
+// using it are skipped rather than raising a finding on missing data.
+func (r *Report) facts() map[string]interface{} {
+	facts := map[string]interface{}{
+		"records.count": float64(len(r.Records)),
+	}
+	domain := fqdn(r.Domain)
+	count := func(fact, rtype string) {
+		if !r.lookupFailed(domain, rtype) {
+			facts[fact] = float64(len(r.values(rtype)))
+		}
+	}
+	count("mx.count", "MX")
+	count("ns.count", "NS")
+	count("caa.count", "CAA")
+	if _, ok := facts["caa.count"]; ok {
+		problems := []string{}
+		for _, caa := range r.values("CAA") {
+			for _, problem := range caaProblems(caa) {
+				problems = append(problems, caa+": "+problem)
+			}
+		}
+		facts["caa.errors"] = problems
+	}
+
+	if _, ok := facts["ns.count"]; ok {
+		providers := map[string]bool{}
+		for _, ns := range r.NS {
+			providers[nsProvider(ns)] = true
+		}
+		facts["ns.providers"] = float64(len(providers))
+	}
+
+	if r.Has("spf") && !r.lookupFailed(domain, "TXT") {
+		facts["spf.count"] = float64(len(r.SPF))
+		facts["spf.all"] = ""
+		if len(r.SPF) > 0 {
+			facts["spf.all"] = spfAll(r.SPF[0])
+		}
+		problems := []string{}
+		for _, spf := range r.SPF {
+			problems = append(problems, spfProblems(spf)...)
+		}
+		facts["spf.errors"] = problems
+		facts["spf.lookups"] = float64(r.SPFLookups)
+	}
+	if r.Has("spf") && !r.lookupFailed("_dmarc."+domain, "TXT") {
+		facts["dmarc.count"] = float64(len(r.DMARC))
+		facts["dmarc.policy"] = ""
+		if len(r.DMARC) > 0 {
+			facts["dmarc.policy"] = dmarcTag(r.DMARC[0], "p")
+		}
+		problems := []string{}
+		for _, dmarc := range r.DMARC {
+			problems = append(problems, dmarcProblems(dmarc)...)
+		}
+		facts["dmarc.errors"] = problems
+	}
+
+	if r.Has("dnssec") && r.DNSSEC != nil && r.DNSSEC.Status != "unknown" {
+		facts["dnssec.status"] = r.DNSSEC.Status
+	}
+
+	if r.Has("cname") {
+		facts["cname.dangling"] = r.Takeover != nil && r.Takeover.NXDOMAIN
+		facts["cname.loop"] = r.CNAME != nil && (r.CNAME.Loop || r.CNAME.Self || r.CNAME.TooLong)
+		conflicts := []string{}
+		if r.CNAME != nil {
+			for _, hop := range r.CNAME.Hops {
+				if hop.Apex {
+					conflicts = append(conflicts, hop.Name+" is a CNAME at the zone apex")
+				}
+				if len(hop.Conflicts) > 0 {
+					conflicts = append(conflicts, hop.Name+" has a CNAME alongside "+strings.Join(hop.Conflicts, ", "))
+				}
+			}
+		}
+		facts["cname.conflicts"] = conflicts
+		facts["cname.takeover_risk"] = "None"
+		if r.Takeover != nil {
+			facts["cname.takeover_risk"] = r.Takeover.Risk
+		}
+	}
+
+	if r.Has("blocklists") {
+		web := []string{}
+		for _, addr := range r.Addresses {
+			web = append(web, listings(addr.Blocklists)...)
+		}
+		facts["web.blocklisted"] = web
+		mail := []string{}
+		for _, mx := range r.MX {
+			mail = append(mail, listings(mx.Blocklists)...)
+		}
+		facts["mx.blocklisted"] = mail
+		if r.DomainBlocklists != nil {
+			facts["domain.blocklisted"] = listings(r.DomainBlocklists.Results)
+		}
+	}
+
+	if r.Has("reverse") {
+		failed := []string{}
+		for _, mx := range r.MX {
+			for _, res := range mx.Reverse {
+				if !res.Pass() {
+					failed = append(failed, fmt.Sprintf("%s (%s)", res.IP, strings.TrimSuffix(mx.Host, ".")))
+				}
+			}
+		}
+		facts["mx.fcrdns_failed"] = failed
+	}
+
+	if r.Has("zonetransfer") || r.Has("axfr") {
+		open := map[string]bool{}
+		for _, check := range r.ZoneTransfer {
+			if check.AXFR || check.IXFR {
+				open[check.Server] = true
+			}
+		}
+		for _, check := range r.AXFR {
+			if check.Allowed {
+				open[check.Server] = true
+			}
+		}
+		servers := []string{}
+		for server := range open {
+			servers = append(servers, server)
+		}
+		sort.Strings(servers)
+		facts["zone.transfer_open"] = servers
+	}
+
+	if r.Has("amplification") {
+		factor := 0.0
+		for _, check := range r.Amplification {
+			if check.Factor > factor {
+				factor = check.Factor
+			}
+		}
+		facts["amplification.factor"] = factor
+	}
+	return facts
+}
+
+// lookupFailed reports whether the report's query for name and rtype got
+// no usable answer.
+func (r *Report) lookupFailed(name, rtype string) bool {
+	for _, l := range r.Lookups {
+		if l.Type == rtype && strings.EqualFold(l.Name, name) && l.Failed() {
+			return true
+		}
+	}
+	return false
+}
+
+func listings(results []*BlocklistResult) []string {
+	listed := []string{}
+	for _, res := range results {
+		if res.Listed {
+			listed = append(listed, fmt.Sprintf("%s on %s", res.Target, res.List))
+		}
+	}
+	return listed
+}
+
+// nsProvider names the operator of a nameserver: its service, or the last
+// two labels of its name when the service is not known.
+func nsProvider(ns NSInfo) string {
+	if ns.Service != "" && ns.Service != "Other" {
+		return ns.Service
+	}
+	labels := strings.Split(strings.ToLower(strings.TrimSuffix(ns.Host, ".")), ".")
+	if len(labels) > 2 {
+		labels = labels[len(labels)-2:]
+	}
+	return strings.Join(labels, ".")
+}
+
+// spfAll returns the all mechanism of an SPF record with its qualifier,
+// or "" when the record has none.
+func spfAll(record string) string {
+	for _, mech := range strings.Fields(record)[1:] {
+		switch strings.ToLower(mech) {
+		case "all", "+all":
+			return "+all"
+		case "-all", "~all", "?all":
+			return strings.ToLower(mech)
+		}
+	}
+	return ""
+}
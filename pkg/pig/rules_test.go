@@ -0,0 +1,207 @@
+package pig
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConditionHolds(t *testing.T) {
+	tests := []struct {
+		cond  Condition
+		value interface{}
+		want  bool
+	}{
+		{Condition{Op: "eq", Value: "Bogus"}, "bogus", true},
+		{Condition{Op: "eq", Value: "bogus"}, "secure", false},
+		{Condition{Op: "eq", Value: 1.0}, 1.0, true},
+		{Condition{Op: "eq", Value: "1"}, 1.0, false},
+		{Condition{Op: "eq", Value: true}, true, true},
+		{Condition{Op: "ne", Value: "none"}, "reject", true},
+		{Condition{Op: "ne", Value: 2.0}, 2.0, false},
+		{Condition{Op: "lt", Value: 2.0}, 1.0, true},
+		{Condition{Op: "lt", Value: 2.0}, 2.0, false},
+		{Condition{Op: "le", Value: 2.0}, 2.0, true},
+		{Condition{Op: "gt", Value: 10.0}, 11.0, true},
+		{Condition{Op: "gt", Value: 10.0}, 10.0, false},
+		{Condition{Op: "ge", Value: 10.0}, 10.0, true},
+		{Condition{Op: "ge", Value: 10.0}, "10", false},
+		{Condition{Op: "in", Value: []interface{}{"none", "quarantine"}}, "None", true},
+		{Condition{Op: "in", Value: []interface{}{"none", "quarantine"}}, "reject", false},
+		{Condition{Op: "contains", Value: "ns1."}, []string{"ns1.", "ns2."}, true},
+		{Condition{Op: "contains", Value: "ns3."}, []string{"ns1.", "ns2."}, false},
+		{Condition{Op: "empty"}, []string{}, true},
+		{Condition{Op: "empty"}, []string{"x"}, false},
+		{Condition{Op: "nonempty"}, []string{"x"}, true},
+		{Condition{Op: "nonempty"}, []string(nil), false},
+	}
+	for _, tt := range tests {
+		if got := tt.cond.holds(tt.value); got != tt.want {
+			t.Errorf("%s %v against %#v = %v, want %v", tt.cond.Op, tt.cond.Value, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestLetterGrade(t *testing.T) {
+	for score, want := range map[int]string{100: "A", 90: "A", 89: "B", 80: "B", 79: "C", 70: "C", 69: "D", 60: "D", 59: "F", 0: "F"} {
+		if got := letterGrade(score); got != want {
+			t.Errorf("letterGrade(%d) = %s, want %s", score, got, want)
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	rule := func(id, category string, severity Severity, when ...Condition) Rule {
+		return Rule{ID: id, Category: category, Severity: severity, Title: id, When: when}
+	}
+	s := &Scanner{rules: []Rule{
+		rule("ns-single", "dns", SeverityHigh, Condition{Fact: "ns.count", Op: "eq", Value: 1.0}),
+		rule("dnssec-bogus", "dns", SeverityCritical, Condition{Fact: "dnssec.status", Op: "eq", Value: "bogus"}),
+		rule("amplification", "dns", SeverityLow, Condition{Fact: "amplification.factor", Op: "gt", Value: 20.0}),
+		rule("mx-blocklisted", "mail", SeverityHigh, Condition{Fact: "mx.blocklisted", Op: "nonempty"}),
+		rule("spf-pass-all", "mail", SeverityCritical, Condition{Fact: "spf.all", Op: "eq", Value: "+all"}),
+		rule("web-info", "web", SeverityInfo, Condition{Fact: "records.count", Op: "ge", Value: 1.0}),
+		rule("web-low", "web", SeverityLow,
+			Condition{Fact: "records.count", Op: "ge", Value: 1.0},
+			Condition{Fact: "records.count", Op: "gt", Value: 5.0}),
+	}}
+	report := &Report{
+		Domain:   "example.com",
+		Sections: []string{"ns", "dnssec", "blocklists"},
+		Records:  []Record{{Name: "example.com.", Type: "NS", Data: "ns1.example.com."}},
+		NS:       []NSInfo{{Host: "ns1.example.com."}},
+		DNSSEC:   &DNSSECStatus{Status: "bogus"},
+		MX:       []MXInfo{{Host: "mail.example.com.", Blocklists: []*BlocklistResult{{List: "Listed", Target: "192.0.2.25", Listed: true}}}},
+	}
+
+	card := s.Score(report)
+	got := []string{}
+	for _, f := range card.Findings {
+		got = append(got, f.ID)
+	}
+	// Most severe first; amplification and spf were not checked, and
+	// web-low has a condition that does not hold.
+	if want := []string{"dnssec-bogus", "ns-single", "mx-blocklisted", "web-info"}; !equalStrings(got, want) {
+		t.Errorf("findings %v, want %v", got, want)
+	}
+	if f := card.Findings[2]; !equalStrings(f.Evidence, []string{"192.0.2.25 on Listed"}) {
+		t.Errorf("mx-blocklisted evidence %v", f.Evidence)
+	}
+	grades := []string{}
+	for _, g := range card.Grades {
+		grades = append(grades, g.Category+" "+g.Grade)
+	}
+	if want := []string{"dns F", "mail B", "web A"}; !equalStrings(grades, want) {
+		t.Errorf("grades %v, want %v", grades, want)
+	}
+	if g := card.Grades[0]; g.Score != 40 || g.Findings != 2 {
+		t.Errorf("dns scored %d with %d findings, want 40 with 2", g.Score, g.Findings)
+	}
+	if g := card.Grades[2]; g.Score != 100 || g.Findings != 1 {
+		t.Errorf("web scored %d with %d findings, want 100 with 1", g.Score, g.Findings)
+	}
+
+	// Scores stop at zero.
+	s.rules = append(s.rules, rule("dnssec-not-secure", "dns", SeverityCritical, Condition{Fact: "dnssec.status", Op: "ne", Value: "secure"}))
+	if g := s.Score(report).Grades[0]; g.Score != 0 || g.Grade != "F" {
+		t.Errorf("dns scored %d (%s), want 0 (F)", g.Score, g.Grade)
+	}
+
+	// A failed lookup leaves its facts out, and a category with no rule
+	// evaluated gets no grade.
+	report.Sections = []string{"ns"}
+	report.Lookups = []Lookup{{Name: "example.com.", Type: "NS", Outcome: OutcomeServFail}}
+	card = s.Score(report)
+	for _, f := range card.Findings {
+		if f.Category == "dns" || f.Category == "mail" {
+			t.Errorf("unexpected finding %s without its facts", f.ID)
+		}
+	}
+	if len(card.Grades) != 1 || card.Grades[0].Category != "web" {
+		t.Errorf("grades %+v, want only web", card.Grades)
+	}
+
+	if card := s.Score(&Report{Domain: "example.com"}); card != nil {
+		t.Errorf("a domain without records was scored: %+v", card)
+	}
+}
+
+func writeRules(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadRulesOverride(t *testing.T) {
+	builtin, err := loadRules(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := writeRules(t, `{"version": 1, "rules": [
+		{"id": "ns-single", "category": "dns", "severity": "low", "title": "One NS", "description": "d", "when": [{"fact": "ns.count", "op": "lt", "value": 2}]},
+		{"id": "dnssec-missing", "disabled": true},
+		{"id": "local-mx", "category": "mail", "severity": "medium", "title": "No MX", "description": "d", "when": [{"fact": "mx.count", "op": "eq", "value": 0}]}
+	]}`)
+	rules, err := loadRules([]string{path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	byID := map[string]Rule{}
+	for _, r := range rules {
+		byID[r.ID] = r
+	}
+	if len(rules) != len(builtin) {
+		t.Errorf("%d rules after one replaced, one disabled and one added, want %d", len(rules), len(builtin))
+	}
+	if r := byID["ns-single"]; r.Severity != SeverityLow || r.Title != "One NS" {
+		t.Errorf("ns-single was not replaced: %+v", r)
+	}
+	if _, ok := byID["dnssec-missing"]; ok {
+		t.Error("dnssec-missing was not disabled")
+	}
+	if _, ok := byID["local-mx"]; !ok {
+		t.Error("local-mx was not added")
+	}
+	if rules[len(rules)-1].ID != "local-mx" {
+		t.Errorf("the new rule is not last: %s", rules[len(rules)-1].ID)
+	}
+
+	s, err := NewScanner(Options{RuleFiles: []string{path}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	card := s.Score(&Report{Domain: "example.com", Sections: []string{"dnssec"},
+		Records: []Record{{Name: "example.com.", Type: "A", Data: "192.0.2.1"}}, DNSSEC: &DNSSECStatus{Status: "insecure"}})
+	for _, f := range card.Findings {
+		if f.ID == "dnssec-missing" {
+			t.Error("a disabled rule raised a finding")
+		}
+	}
+}
+
+func TestLoadRulesErrors(t *testing.T) {
+	for _, tt := range []struct {
+		rules string
+		want  string
+	}{
+		{`{"version": 2, "rules": []}`, "unsupported rules version"},
+		{`{"rules": [{"category": "dns"}]}`, "has no id"},
+		{`{"rules": [{"id": "x", "category": "dns", "severity": "low", "when": []}]}`, "has no conditions"},
+		{`{"rules": [{"id": "x", "category": "ftp", "severity": "low", "when": [{"fact": "ns.count", "op": "eq", "value": 1}]}]}`, "unknown category"},
+		{`{"rules": [{"id": "x", "category": "dns", "severity": "dire", "when": [{"fact": "ns.count", "op": "eq", "value": 1}]}]}`, "unknown severity"},
+		{`{"rules": [{"id": "x", "category": "dns", "severity": "low", "when": [{"fact": "ns.color", "op": "eq", "value": 1}]}]}`, "unknown fact"},
+		{`{"rules": [{"id": "x", "category": "dns", "severity": "low", "when": [{"fact": "ns.count", "op": "like", "value": 1}]}]}`, "unknown op"},
+		{`{"rules": [{"id": "x", "category": "dns", "severity": "low", "when": [{"fact": "spf.all", "op": "gt", "value": 1}]}]}`, "is not a number"},
+		{`{"rules": [{"id": "x", "category": "dns", "severity": "low", "when": [{"fact": "ns.count", "op": "gt", "value": "1"}]}]}`, "needs a number"},
+		{`{"rules": [{"id": "x", "category": "dns", "severity": "low", "when": [{"fact": "ns.count", "op": "eq"}]}]}`, "needs a value"},
+		{`{"rules": [{"id": "x", "category": "dns", "severity": "low", "when": [{"fact": "ns.count", "op": "in", "value": 1}]}]}`, "needs a list"},
+		{`{"rules": [{"id": "x", "category": "dns", "severity": "low", "when": [{"fact": "ns.count", "op": "nonempty"}]}]}`, "is not a list"},
+	} {
+		if _, err := loadRules([]string{writeRules(t, tt.rules)}); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error %v, want %q", tt.rules, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+// Package pig gathers DNS intelligence about domains and IP addresses:
+// records, mail and DNS providers, CNAME chains and takeover risk, ASN,
+// geolocation and blocklist data, DNSSEC status and zone transfer checks.
+//
+// A Scanner runs the checks. Report runs all of them for a domain, and the
+// individual methods run one check each.
+package pig
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Options configure a Scanner. The zero value uses the system resolver, the
+// built-in service, blocklist and scoring rule tables, and no geolocation
+// or passive DNS.
+type Options struct {
+	// Resolver is the recursive resolver to query: host or host:port for
+	// plain DNS, tls://host[:port] for DNS over TLS, or an https:// URL for
+	// DNS over HTTPS, sent by POST unless the URL is a template ending in
+	// {?dns}. It defaults to the first nameserver in /etc/resolv.conf.
+	Resolver string
+
+	// TLSConfig is used for DNS over TLS and HTTPS resolvers. Nil verifies
+	// their certificates against the system roots.
+	TLSConfig *tls.Config
+
+	// ProbeHTTP fetches CNAME targets over HTTP to look for the
+	// "unclaimed resource" pages used by the takeover check.
+	ProbeHTTP bool
+
+	// ServiceFiles and DNSBLFiles are applied over the built-in service
+	// fingerprints and blocklists, in order.
+	ServiceFiles []string
+	DNSBLFiles   []string
+
+	// RuleFiles are applied over the built-in scoring rules, in order.
+	RuleFiles []string
+
+	// PassiveDNSFiles are CSV or JSON Lines passive DNS exports.
+	PassiveDNSFiles []string
+
+	// Geo locates addresses. Nil disables geolocation.
+	Geo GeoProvider
+
+	// Timeout bounds each DNS query. It defaults to 5 seconds.
+	Timeout time.Duration
+
+	// Profile limits the probes a scan may send. It defaults to Standard.
+	Profile Profile
+}
+
+// A Scanner is safe for concurrent use and caches ASN lookups across calls.
+type Scanner struct {
+	opts      Options
+	resolver  string
+	transport transport
+	timeout   time.Duration
+	profile   Profile
+	services  *serviceDB
+	dnsbl     []dnsblList
+	rules     []Rule
+	passive   *pdnsIndex
+	asn       *asnCache
+	log       *LookupLog
+	client    *http.Client
+	// limit, when set, is received from before every query.
+	limit <-chan time.Time
+}
+
+type asnCache struct {
+	mu     sync.Mutex
+	byIP   map[string]*ASNInfo
+	byName map[string]string
+}
+
+// NewScanner loads the service, blocklist and passive DNS files named in
+// opts. Files in ~/.config/pig are not read; callers add them to opts.
+func NewScanner(opts Options) (*Scanner, error) {
+	s := &Scanner{
+		opts:     opts,
+		resolver: opts.Resolver,
+		timeout:  opts.Timeout,
+		profile:  opts.Profile,
+		asn:      &asnCache{byIP: make(map[string]*ASNInfo), byName: make(map[string]string)},
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+	if s.resolver == "" {
+		s.resolver = SystemResolver()
+	}
+	var err error
+	if s.transport, s.resolver, err = newTransport(s.resolver, opts.TLSConfig); err != nil {
+		return nil, err
+	}
+
+	if s.timeout <= 0 {
+		s.timeout = 5 * time.Second
+	}
+	switch s.profile {
+	case "":
+		s.profile = Standard
+	case Passive, Standard, Active:
+	default:
+		return nil, fmt.Errorf("unknown scan profile %q", s.profile)
+	}
+
+	if s.services, err = loadServiceDB(opts.ServiceFiles); err != nil {
+		return nil, fmt.Errorf("loading service fingerprints: %w", err)
+	}
+	if s.dnsbl, err = loadDNSBLConfig(opts.DNSBLFiles); err != nil {
+		return nil, fmt.Errorf("loading DNSBL config: %w", err)
+	}
+	if s.rules, err = loadRules(opts.RuleFiles); err != nil {
+		return nil, fmt.Errorf("loading rules: %w", err)
+	}
+	if len(opts.PassiveDNSFiles) > 0 {
+		if s.passive, err = loadPassiveDNS(opts.PassiveDNSFiles); err != nil {
+			return nil, fmt.Errorf("loading passive DNS: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// Profile returns the scan profile of the scanner.
+func (s *Scanner) Profile() Profile {
+	return s.profile
+}
+
+// Resolver returns the host:port or URL the scanner sends queries to.
+func (s *Scanner) Resolver() string {
+	return s.resolver
+}
+
+// query sends one question to the resolver.
+func (s *Scanner) query(name string, qtype uint16) (*dnsMsg, error) {
+	return s.exchange(name, qtype, 0)
+}
+
+func (s *Scanner) exchange(name string, qtype uint16, flags uint16) (*dnsMsg, error) {
+	return s.exchangeWith(s.resolver, name, qtype, flags)
+}
+
+// exchangeWith sends one question to server, which is the resolver or a
+// nameserver of the domain.
+func (s *Scanner) exchangeWith(server, name string, qtype uint16, flags uint16) (*dnsMsg, error) {
+	if s.limit != nil {
+		<-s.limit
+	}
+	start := time.Now()
+	var msg *dnsMsg
+	var err error
+	if server == s.resolver {
+		msg, err = s.transport.exchange(name, qtype, flags, s.timeout)
+	} else {
+		msg, err = dnsExchange(server, name, qtype, flags, s.timeout)
+	}
+	if s.log != nil {
+		l := newLookup(name, qtype, msg, err, time.Since(start))
+		if server != s.resolver {
+			l.Server = server
+		}
+		s.log.add(l)
+	}
+	return msg, err
+}
+
+// WithLog returns a copy of s that records the outcome of every query it
+// sends in log. The copy shares the ASN cache of s.
+func (s *Scanner) WithLog(log *LookupLog) *Scanner {
+	c := *s
+	c.log = log
+	return &c
+}
+
+// lookupIP returns the A and AAAA addresses of host, following CNAMEs.
+func (s *Scanner) lookupIP(host string) []net.IP {
+	ips := []net.IP{}
+	for _, t := range []uint16{typeA, typeAAAA} {
+		msg, err := s.query(host, t)
+		if err != nil {
+			continue
+		}
+		for _, rr := range msg.Answer {
+			if rr.Type == t {
+				ips = append(ips, net.ParseIP(rr.Data))
+			}
+		}
+	}
+	return ips
+}
+
+func (s *Scanner) lookupTXT(name string) []string {
+	msg, err := s.query(name, typeTXT)
+	if err != nil {
+		return nil
+	}
+	txts := []string{}
+	for _, rr := range msg.Answer {
+		if rr.Type == typeTXT {
+			txts = append(txts, rr.Data)
+		}
+	}
+	return txts
+}
+
+// lookupCNAME returns the CNAME target of host, or "" when it has none.
+func (s *Scanner) lookupCNAME(host string) string {
+	msg, err := s.query(host, typeCNAME)
+	if err != nil {
+		return ""
+	}
+	for _, rr := range msg.answers(host, typeCNAME) {
+		return rr.Data
+	}
+	return ""
+}
+
+// DetectService names the provider behind a hostname, or "Other".
+func (s *Scanner) DetectService(host string) string {
+	if fp := s.services.match(host); fp != nil {
+		return fp.Service
+	}
+	return "Other"
+}
+
+// MatchService returns the fingerprint matching a hostname, or nil.
+func (s *Scanner) MatchService(host string) *ServiceFingerprint {
+	return s.services.match(host)
+}
@@ -0,0 +1,234 @@
+package pig
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testServer is a DNS server on a local UDP port that answers from records
+// written as "name ttl type data". Unknown names get NXDOMAIN, and CNAMEs
+// are followed the way a recursive resolver would.
+type testServer struct {
+	addr          string
+	authoritative bool
+
+	mu      sync.Mutex
+	records map[string][]testRR
+	names   map[string]bool
+	rcodes  map[string]int
+	queries []string
+}
+
+type testRR struct {
+	name  string
+	ttl   uint32
+	qtype uint16
+	rdata []byte
+}
+
+func newTestServer(t *testing.T, authoritative bool, records ...string) *testServer {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	srv := &testServer{
+		addr:          conn.LocalAddr().String(),
+		authoritative: authoritative,
+		records:       map[string][]testRR{},
+		names:         map[string]bool{},
+		rcodes:        map[string]int{},
+	}
+	for _, record := range records {
+		srv.add(t, record)
+	}
+	go srv.serve(conn)
+	return srv
+}
+
+func (srv *testServer) add(t *testing.T, record string) {
+	t.Helper()
+	f := strings.SplitN(record, " ", 4)
+	if len(f) != 4 {
+		t.Fatalf("bad test record %q", record)
+	}
+	ttl, err := strconv.Atoi(f[1])
+	if err != nil {
+		t.Fatalf("bad TTL in %q", record)
+	}
+	qtype := typeCode(f[2])
+	rdata, err := packTestRdata(qtype, f[3])
+	if err != nil {
+		t.Fatalf("bad test record %q: %v", record, err)
+	}
+	name := fqdn(f[0])
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	key := name + " " + f[2]
+	srv.records[key] = append(srv.records[key], testRR{name: name, ttl: uint32(ttl), qtype: qtype, rdata: rdata})
+	srv.names[name] = true
+}
+
+// fail makes the server answer queries for name and type with rcode.
+func (srv *testServer) fail(name, qtype string, rcode int) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.rcodes[fqdn(name)+" "+qtype] = rcode
+}
+
+// count returns how many queries the server has answered.
+func (srv *testServer) count() int {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return len(srv.queries)
+}
+
+func (srv *testServer) serve(conn net.PacketConn) {
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if resp := srv.answer(buf[:n]); resp != nil {
+			conn.WriteTo(resp, addr)
+		}
+	}
+}
+
+func (srv *testServer) answer(query []byte) []byte {
+	name, off, err := unpackName(query, 12)
+	if err != nil || off+4 > len(query) {
+		return nil
+	}
+	name = strings.ToLower(name)
+	qtype := binary.BigEndian.Uint16(query[off:])
+	key := name + " " + typeString(qtype)
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.queries = append(srv.queries, key)
+	rcode, ok := srv.rcodes[key]
+	var answers []testRR
+	if !ok {
+		answers, rcode = srv.lookup(name, qtype)
+	}
+
+	flags := uint16(0x8180) | uint16(rcode)
+	if srv.authoritative {
+		flags |= 0x0400
+	}
+	resp := make([]byte, 12, 512)
+	copy(resp, query[:2])
+	binary.BigEndian.PutUint16(resp[2:], flags)
+	binary.BigEndian.PutUint16(resp[4:], 1)
+	binary.BigEndian.PutUint16(resp[6:], uint16(len(answers)))
+	resp = append(resp, query[12:off+4]...)
+	for _, rr := range answers {
+		resp, _ = packName(resp, rr.name)
+		resp = binary.BigEndian.AppendUint16(resp, rr.qtype)
+		resp = binary.BigEndian.AppendUint16(resp, classINET)
+		resp = binary.BigEndian.AppendUint32(resp, rr.ttl)
+		resp = binary.BigEndian.AppendUint16(resp, uint16(len(rr.rdata)))
+		resp = append(resp, rr.rdata...)
+	}
+	return resp
+}
+
+func (srv *testServer) lookup(name string, qtype uint16) ([]testRR, int) {
+	answers := []testRR{}
+	for hops := 0; hops < 8; hops++ {
+		if rrs := srv.records[name+" "+typeString(qtype)]; len(rrs) > 0 {
+			return append(answers, rrs...), rcodeSuccess
+		}
+		cnames := srv.records[name+" CNAME"]
+		if len(cnames) < 1 {
+			break
+		}
+		answers = append(answers, cnames[0])
+		name, _, _ = unpackName(cnames[0].rdata, 0)
+	}
+	if len(answers) > 0 || srv.names[name] {
+		return answers, rcodeSuccess
+	}
+	return answers, rcodeNXDomain
+}
+
+func typeCode(name string) uint16 {
+	for code, n := range typeNames {
+		if n == name {
+			return code
+		}
+	}
+	return 0
+}
+
+func packTestRdata(qtype uint16, data string) ([]byte, error) {
+	f := strings.Fields(data)
+	uint16s := func(fields []string) []byte {
+		b := []byte{}
+		for _, field := range fields {
+			n, _ := strconv.Atoi(field)
+			b = binary.BigEndian.AppendUint16(b, uint16(n))
+		}
+		return b
+	}
+	switch qtype {
+	case typeA:
+		return net.ParseIP(data).To4(), nil
+	case typeAAAA:
+		return net.ParseIP(data).To16(), nil
+	case typeNS, typeCNAME, typePTR:
+		return packName(nil, data)
+	case typeMX:
+		return packName(uint16s(f[:1]), f[1])
+	case typeSRV:
+		return packName(uint16s(f[:3]), f[3])
+	case typeSOA:
+		b, err := packName(nil, f[0])
+		if err != nil {
+			return nil, err
+		}
+		if b, err = packName(b, f[1]); err != nil {
+			return nil, err
+		}
+		for _, field := range f[2:] {
+			n, _ := strconv.ParseUint(field, 10, 32)
+			b = binary.BigEndian.AppendUint32(b, uint32(n))
+		}
+		return b, nil
+	case typeTXT:
+		b := []byte{}
+		for len(data) > 255 {
+			b = append(append(b, 255), data[:255]...)
+			data = data[255:]
+		}
+		return append(append(b, byte(len(data))), data...), nil
+	case typeCAA:
+		flags, _ := strconv.Atoi(f[0])
+		b := append([]byte{byte(flags), byte(len(f[1]))}, f[1]...)
+		return append(b, strings.Trim(strings.Join(f[2:], " "), `"`)...), nil
+	}
+	return hex.DecodeString(data)
+}
+
+// newTestScanner returns a scanner that uses srv as its resolver.
+func newTestScanner(t *testing.T, srv *testServer, opts Options) *Scanner {
+	t.Helper()
+	opts.Resolver = srv.addr
+	if opts.Timeout == 0 {
+		opts.Timeout = 2 * time.Second
+	}
+	s, err := NewScanner(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
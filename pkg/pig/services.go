@@ -0,0 +1,242 @@
+package pig
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+//go:embed services.json
+var servicesJSON []byte
+
+const servicesVersion = 3
+
+var matchRank = map[string]int{
+	"exact":  0,
+	"suffix": 1,
+	"regex":  2,
+	"cidr":   3,
+	"asn":    4,
+	"txt":    5,
+}
+
+type ServiceFingerprint struct {
+	Service  string `json:"service"`
+	Category string `json:"category"`
+	Match    string `json:"match"`
+	Pattern  string `json:"pattern"`
+	Priority int    `json:"priority,omitempty"`
+
+	re    *regexp.Regexp
+	ipnet *net.IPNet
+}
+
+// A takeoverProvider describes a hosting provider whose resources can be
+// claimed by anyone once they are deleted. Targets match on Suffixes or on
+// the regular expressions in Patterns. Fingerprints are phrases of the
+// provider's "unclaimed resource" page, and NXDOMAIN marks providers whose
+// dangling names stop resolving instead.
+type takeoverProvider struct {
+	Provider     string   `json:"provider"`
+	Suffixes     []string `json:"suffixes"`
+	Patterns     []string `json:"patterns,omitempty"`
+	Fingerprints []string `json:"fingerprints,omitempty"`
+	NXDOMAIN     bool     `json:"nxdomain,omitempty"`
+
+	res []*regexp.Regexp
+}
+
+type serviceDB struct {
+	Version      int                  `json:"version"`
+	Fingerprints []ServiceFingerprint `json:"fingerprints"`
+	Takeover     []takeoverProvider   `json:"takeover,omitempty"`
+}
+
+// loadServiceDB reads the embedded fingerprints followed by each override
+// file. An override entry with the same match kind and pattern as an earlier
+// one replaces it.
+func loadServiceDB(paths []string) (*serviceDB, error) {
+	db, err := parseServiceDB(servicesJSON)
+	if err != nil {
+		return nil, fmt.Errorf("embedded services.json: %w", err)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		override, err := parseServiceDB(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		db.merge(override)
+	}
+	db.sort()
+	return db, nil
+}
+
+func parseServiceDB(data []byte) (*serviceDB, error) {
+	var db serviceDB
+	if err := json.Unmarshal(data, &db); err != nil {
+		return nil, err
+	}
+	if db.Version > servicesVersion {
+		return nil, fmt.Errorf("unsupported fingerprint database version %d", db.Version)
+	}
+	for i := range db.Fingerprints {
+		fp := &db.Fingerprints[i]
+		if _, ok := matchRank[fp.Match]; !ok {
+			return nil, fmt.Errorf("fingerprint %q: unknown match kind %q", fp.Pattern, fp.Match)
+		}
+		switch fp.Match {
+		case "regex":
+			re, err := regexp.Compile(fp.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("fingerprint %q: %w", fp.Pattern, err)
+			}
+			fp.re = re
+		case "cidr":
+			_, ipnet, err := net.ParseCIDR(fp.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("fingerprint %q: %w", fp.Pattern, err)
+			}
+			fp.ipnet = ipnet
+		case "asn":
+			fp.Pattern = strings.TrimPrefix(strings.ToUpper(fp.Pattern), "AS")
+		case "txt":
+		default:
+			fp.Pattern = strings.ToLower(strings.TrimSuffix(fp.Pattern, "."))
+		}
+	}
+	for i := range db.Takeover {
+		p := &db.Takeover[i]
+		for j, suffix := range p.Suffixes {
+			p.Suffixes[j] = strings.ToLower(strings.TrimSuffix(suffix, "."))
+		}
+		for _, pattern := range p.Patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("takeover provider %q: %w", p.Provider, err)
+			}
+			p.res = append(p.res, re)
+		}
+	}
+	return &db, nil
+}
+
+// merge applies an override database. Takeover providers are replaced by
+// name.
+func (db *serviceDB) merge(override *serviceDB) {
+	index := make(map[string]int)
+	for i, fp := range db.Fingerprints {
+		index[fp.Match+" "+fp.Pattern] = i
+	}
+	for _, fp := range override.Fingerprints {
+		if i, ok := index[fp.Match+" "+fp.Pattern]; ok {
+			db.Fingerprints[i] = fp
+			continue
+		}
+		index[fp.Match+" "+fp.Pattern] = len(db.Fingerprints)
+		db.Fingerprints = append(db.Fingerprints, fp)
+	}
+
+	providers := make(map[string]int)
+	for i, p := range db.Takeover {
+		providers[p.Provider] = i
+	}
+	for _, p := range override.Takeover {
+		if i, ok := providers[p.Provider]; ok {
+			db.Takeover[i] = p
+			continue
+		}
+		providers[p.Provider] = len(db.Takeover)
+		db.Takeover = append(db.Takeover, p)
+	}
+}
+
+// sort orders fingerprints so the first match wins: higher priority first,
+// then exact before suffix before regex, then the longest suffix or prefix.
+func (db *serviceDB) sort() {
+	sort.SliceStable(db.Fingerprints, func(i, j int) bool {
+		a, b := db.Fingerprints[i], db.Fingerprints[j]
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		if matchRank[a.Match] != matchRank[b.Match] {
+			return matchRank[a.Match] < matchRank[b.Match]
+		}
+		if a.Match == "cidr" {
+			aOnes, _ := a.ipnet.Mask.Size()
+			bOnes, _ := b.ipnet.Mask.Size()
+			return aOnes > bOnes
+		}
+		return len(a.Pattern) > len(b.Pattern)
+	})
+}
+
+func (db *serviceDB) match(domain string) *ServiceFingerprint {
+	host := strings.ToLower(strings.TrimSuffix(domain, "."))
+	return db.find(func(fp *ServiceFingerprint) bool {
+		switch fp.Match {
+		case "exact":
+			return host == fp.Pattern
+		case "suffix":
+			return host == fp.Pattern || strings.HasSuffix(host, "."+fp.Pattern)
+		case "regex":
+			return fp.re.MatchString(host)
+		}
+		return false
+	})
+}
+
+func (db *serviceDB) matchIP(ip net.IP) *ServiceFingerprint {
+	return db.find(func(fp *ServiceFingerprint) bool {
+		return fp.Match == "cidr" && fp.ipnet.Contains(ip)
+	})
+}
+
+func (db *serviceDB) matchASN(asn string) *ServiceFingerprint {
+	asn = strings.TrimPrefix(strings.ToUpper(asn), "AS")
+	return db.find(func(fp *ServiceFingerprint) bool {
+		return fp.Match == "asn" && fp.Pattern == asn
+	})
+}
+
+func (db *serviceDB) matchTXT(txt string) *ServiceFingerprint {
+	return db.find(func(fp *ServiceFingerprint) bool {
+		return fp.Match == "txt" && strings.HasPrefix(txt, fp.Pattern)
+	})
+}
+
+// takeoverProvider returns the takeover provider hosting target, or nil.
+func (db *serviceDB) takeoverProvider(target string) *takeoverProvider {
+	host := strings.ToLower(strings.TrimSuffix(target, "."))
+	for i := range db.Takeover {
+		p := &db.Takeover[i]
+		for _, suffix := range p.Suffixes {
+			if host == suffix || strings.HasSuffix(host, "."+suffix) {
+				return p
+			}
+		}
+		for _, re := range p.res {
+			if re.MatchString(host) {
+				return p
+			}
+		}
+	}
+	return nil
+}
+
+func (db *serviceDB) find(matches func(*ServiceFingerprint) bool) *ServiceFingerprint {
+	for i := range db.Fingerprints {
+		if matches(&db.Fingerprints[i]) {
+			return &db.Fingerprints[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package pig
+
+import (
+	"net"
+	"testing"
+)
+
+func TestMatchIP(t *testing.T) {
+	s, err := NewScanner(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		ip, want string
+	}{
+		{"104.16.132.229", "Cloudflare"},
+		{"2606:4700::6810:84e5", "Cloudflare"},
+		{"151.101.1.69", "Fastly CDN"},
+		{"185.199.108.153", "GitHub Pages"},
+		{"3.5.140.2", "Amazon Web Services"},
+		{"18.204.10.1", "Amazon Web Services"},
+		{"52.20.1.1", "Amazon Web Services"},
+		{"54.239.28.85", "Amazon Web Services"},
+		{"2600:1f18:24e6:b900::1", "Amazon Web Services"},
+		{"13.32.99.1", "Amazon CloudFront CDN"},
+		{"54.192.1.1", "Amazon CloudFront CDN"},
+		{"2600:9000:2000::1", "Amazon CloudFront CDN"},
+		{"192.0.2.1", ""},
+		{"18.1.1.1", ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if fp := s.services.matchIP(net.ParseIP(tt.ip)); fp != nil {
+			got = fp.Service
+		}
+		if got != tt.want {
+			t.Errorf("matchIP(%s) = %q, want %q", tt.ip, got, tt.want)
+		}
+	}
+	for _, asn := range []string{"16509", "AS14618", "8987"} {
+		if fp := s.services.matchASN(asn); fp == nil || fp.Service != "Amazon Web Services" {
+			t.Errorf("matchASN(%s) = %+v, want Amazon Web Services", asn, fp)
+		}
+	}
+}
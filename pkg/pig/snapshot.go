@@ -0,0 +1,118 @@
+package pig
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const snapshotLayout = "20060102T150405.000Z"
+
+// A Store keeps report snapshots as JSON files in Dir, one directory per
+// domain, named by scan time.
+type Store struct {
+	Dir string
+}
+
+// DefaultStoreDir returns pig/snapshots under $XDG_DATA_HOME (default
+// ~/.local/share).
+func DefaultStoreDir() (string, error) {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "pig", "snapshots"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share", "pig", "snapshots"), nil
+}
+
+// Save writes report to the store and returns the path of the snapshot.
+// Snapshots are never overwritten: a scan finishing in the same millisecond
+// as an earlier one is named a millisecond later.
+func (st *Store) Save(report *Report) (string, error) {
+	dir, err := st.domainDir(report.Domain)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	for t := report.Time.UTC(); ; t = t.Add(time.Millisecond) {
+		path := filepath.Join(dir, t.Format(snapshotLayout)+".json")
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if _, err := f.Write(append(data, '\n')); err != nil {
+			f.Close()
+			return "", err
+		}
+		return path, f.Close()
+	}
+}
+
+// domainDir returns the directory of domain's snapshots. The domain must be
+// a hostname, so that it cannot name a path outside the store.
+func (st *Store) domainDir(domain string) (string, error) {
+	if !ValidDomain(domain) {
+		return "", fmt.Errorf("invalid domain name %q", domain)
+	}
+	return filepath.Join(st.Dir, strings.TrimSuffix(domain, ".")), nil
+}
+
+// List returns the snapshot IDs of domain, oldest first.
+func (st *Store) List(domain string) ([]string, error) {
+	dir, err := st.domainDir(domain)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	ids := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			ids = append(ids, strings.TrimSuffix(entry.Name(), ".json"))
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Load reads a snapshot by ID, or from a file path when ref names an
+// existing file.
+func (st *Store) Load(domain, ref string) (*Report, error) {
+	path := ref
+	if _, err := os.Stat(ref); err != nil {
+		dir, err := st.domainDir(domain)
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(dir, strings.TrimSuffix(filepath.Base(ref), ".json")+".json")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &report, nil
+}
@@ -0,0 +1,74 @@
+package pig
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStoreSaveSameTime(t *testing.T) {
+	store := &Store{Dir: t.TempDir()}
+	now := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	paths := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		path, err := store.Save(&Report{Domain: "example.com", Time: now})
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths[path] = true
+	}
+	ids, err := store.List("example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"20250101T090000.000Z", "20250101T090000.001Z", "20250101T090000.002Z"}
+	if len(paths) != 3 || len(ids) != 3 {
+		t.Fatalf("three saves in the same millisecond left %v", ids)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Errorf("List = %v, want %v", ids, want)
+			break
+		}
+	}
+	if _, err := store.Load("example.com", ids[2]); err != nil {
+		t.Errorf("Load(%s): %v", ids[2], err)
+	}
+}
+
+func TestStoreDomainNames(t *testing.T) {
+	store := &Store{Dir: t.TempDir()}
+	for _, domain := range []string{"", ".", "..", "../etc", "a/b", `a\b`, "a..b", "/tmp"} {
+		if _, err := store.Save(&Report{Domain: domain, Time: time.Now()}); err == nil {
+			t.Errorf("Save with domain %q succeeded", domain)
+		}
+		if _, err := store.List(domain); err == nil {
+			t.Errorf("List(%q) succeeded", domain)
+		}
+	}
+	for _, domain := range []string{"example.com", "example.com.", "_dmarc.example.com", "xn--bcher-kva.example"} {
+		if _, err := store.Save(&Report{Domain: domain, Time: time.Now()}); err != nil {
+			t.Errorf("Save with domain %q: %v", domain, err)
+		}
+	}
+}
+
+func TestDiffIgnoresTTL(t *testing.T) {
+	old := &Report{Domain: "example.com", Records: []Record{
+		{Name: "example.com.", Type: "A", TTL: 300, Data: "192.0.2.1"},
+		{Name: "www.example.com.", Type: "CNAME", TTL: 3600, Data: "example.com."},
+	}}
+	cur := &Report{Domain: "example.com", Records: []Record{
+		{Name: "example.com.", Type: "A", TTL: 17, Data: "192.0.2.1"},
+		{Name: "www.example.com.", Type: "CNAME", TTL: 2900, Data: "example.com."},
+	}}
+	if d := Diff(old, cur); !d.Empty() {
+		t.Errorf("records differing only in TTL gave diff %+v", d)
+	}
+
+	cur.Records[1].Data = "cdn.example.net."
+	cur.Records = append(cur.Records, Record{Name: "example.com.", Type: "A", TTL: 300, Data: "192.0.2.2"})
+	d := Diff(old, cur)
+	if len(d.Added) != 1 || d.Added[0].Data != "192.0.2.2" || len(d.Changed) != 1 || d.Changed[0].New.Data != "cdn.example.net." {
+		t.Errorf("Diff = %+v, want one added A record and one changed CNAME", d)
+	}
+}
@@ -0,0 +1,196 @@
+package pig
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+type SPFInfo struct {
+	Domain     string   `json:"domain"`
+	Records    []string `json:"records"`
+	Mechanisms []string `json:"mechanisms"`
+	Includes   []string `json:"includes"`
+}
+
+// SPF returns the SPF records of domain with their mechanisms and the
+// domains they include.
+func (s *Scanner) SPF(domain string) (*SPFInfo, error) {
+	msg, err := s.query(domain, typeTXT)
+	if err != nil {
+		return nil, err
+	}
+	info := &SPFInfo{Domain: domain, Records: []string{}, Mechanisms: []string{}}
+	for _, rr := range msg.answers(domain, typeTXT) {
+		if strings.HasPrefix(rr.Data, "v=spf1") {
+			info.Records = append(info.Records, rr.Data)
+			info.Mechanisms = append(info.Mechanisms, strings.Fields(rr.Data)[1:]...)
+		}
+	}
+	info.Includes = spfIncludes(info.Records)
+	return info, nil
+}
+
+// spfLookupLimit is the number of DNS lookups an SPF check may need
+// before it fails with a permanent error (RFC 7208 section 4.6.4).
+const spfLookupLimit = 10
+
+// spfProblems returns the syntax errors and discouraged terms in an SPF
+// record.
+func spfProblems(record string) []string {
+	problems := []string{}
+	terms := strings.Fields(record)
+	if len(terms) < 1 || !strings.EqualFold(terms[0], "v=spf1") {
+		return []string{"record does not start with v=spf1"}
+	}
+	seenAll, redirects, exps := false, 0, 0
+	for _, term := range terms[1:] {
+		if seenAll {
+			problems = append(problems, fmt.Sprintf("%s comes after all and is ignored", term))
+			continue
+		}
+		if name, value, ok := strings.Cut(term, "="); ok && !strings.ContainsAny(name, ":/") {
+			switch strings.ToLower(name) {
+			case "redirect":
+				redirects++
+			case "exp":
+				exps++
+			}
+			if value == "" {
+				problems = append(problems, fmt.Sprintf("%s has no value", term))
+			}
+			continue
+		}
+
+		mech := strings.ToLower(strings.TrimLeft(term, "+-~?"))
+		if len(term)-len(mech) > 1 {
+			problems = append(problems, fmt.Sprintf("%s has more than one qualifier", term))
+		}
+		name, arg, hasArg := strings.Cut(mech, ":")
+		cidr := ""
+		if i := strings.Index(name, "/"); i >= 0 {
+			name, cidr = name[:i], name[i:]
+		}
+		switch name {
+		case "all":
+			if hasArg || cidr != "" {
+				problems = append(problems, fmt.Sprintf("%s takes no argument", term))
+			}
+			seenAll = true
+		case "include", "exists":
+			if arg == "" {
+				problems = append(problems, fmt.Sprintf("%s needs a domain", term))
+			}
+		case "a", "mx":
+			if hasArg && arg == "" {
+				problems = append(problems, fmt.Sprintf("%s has an empty domain", term))
+			}
+			if hasArg {
+				if i := strings.Index(arg, "/"); i >= 0 {
+					cidr = arg[i:]
+				}
+			}
+			if cidr != "" && !validSPFCIDR(cidr) {
+				problems = append(problems, fmt.Sprintf("%s has an invalid prefix length", term))
+			}
+		case "ptr":
+			problems = append(problems, fmt.Sprintf("%s is deprecated and slow, and many receivers ignore it", term))
+		case "ip4", "ip6":
+			addr, bits, _ := strings.Cut(arg, "/")
+			ip := net.ParseIP(addr)
+			if ip == nil || (name == "ip4") != (ip.To4() != nil) {
+				problems = append(problems, fmt.Sprintf("%s is not a valid %s address", term, name))
+				continue
+			}
+			if bits != "" {
+				n, err := strconv.Atoi(bits)
+				if err != nil || n < 0 || (name == "ip4" && n > 32) || n > 128 {
+					problems = append(problems, fmt.Sprintf("%s has an invalid prefix length", term))
+				}
+			}
+		default:
+			problems = append(problems, fmt.Sprintf("unknown mechanism %s", term))
+		}
+	}
+	if redirects > 1 {
+		problems = append(problems, "more than one redirect modifier")
+	}
+	if exps > 1 {
+		problems = append(problems, "more than one exp modifier")
+	}
+	if redirects > 0 && seenAll {
+		problems = append(problems, "redirect is ignored because the record has an all mechanism")
+	}
+	return problems
+}
+
+// validSPFCIDR checks the /ip4-cidr and //ip6-cidr suffix of an a or mx
+// mechanism.
+func validSPFCIDR(cidr string) bool {
+	v4, v6, dual := strings.Cut(strings.TrimPrefix(cidr, "/"), "//")
+	check := func(s string, max int) bool {
+		n, err := strconv.Atoi(s)
+		return err == nil && n >= 0 && n <= max
+	}
+	if strings.HasPrefix(cidr, "//") {
+		return check(strings.TrimPrefix(cidr, "//"), 128)
+	}
+	if !check(v4, 32) {
+		return false
+	}
+	return !dual || check(v6, 128)
+}
+
+// spfLookups counts the DNS lookups an SPF check of record needs: one for
+// each include, a, mx, ptr, exists and redirect, plus the lookups of the
+// records that include and redirect lead to. fetch returns the SPF records
+// of a domain, or nil when they are not known, in which case the term
+// counts as a single lookup.
+func spfLookups(record string, fetch func(domain string) []string) int {
+	seen := map[string]bool{}
+	var count func(record string, depth int) int
+	count = func(record string, depth int) int {
+		n := 0
+		for _, term := range strings.Fields(record)[1:] {
+			term = strings.ToLower(strings.TrimLeft(term, "+-~?"))
+			name, target := term, ""
+			if i := strings.IndexAny(term, ":="); i >= 0 {
+				name, target = term[:i], term[i+1:]
+			}
+			if i := strings.Index(name, "/"); i >= 0 {
+				name = name[:i]
+			}
+			switch name {
+			case "a", "mx", "ptr", "exists":
+				n++
+			case "include", "redirect":
+				n++
+				target = fqdn(target)
+				if seen[target] || depth >= spfLookupLimit {
+					continue
+				}
+				seen[target] = true
+				if records := fetch(target); len(records) == 1 {
+					n += count(records[0], depth+1)
+				}
+			}
+		}
+		return n
+	}
+	return count(record, 0)
+}
+
+// spfLookups counts the lookups of an SPF record, fetching the records it
+// includes from the resolver.
+func (s *Scanner) spfLookups(record string) int {
+	return spfLookups(record, func(domain string) []string {
+		records := []string{}
+		for _, txt := range s.lookupTXT(domain) {
+			if strings.HasPrefix(txt, "v=spf1") {
+				records = append(records, txt)
+			}
+		}
+		return records
+	})
+}
@@ -0,0 +1,200 @@
+package pig
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// SvcParamKeys of RFC 9460 section 14.3.2, and dohpath of RFC 9461.
+const (
+	svcKeyMandatory     = 0
+	svcKeyALPN          = 1
+	svcKeyNoDefaultALPN = 2
+	svcKeyPort          = 3
+	svcKeyIPv4Hint      = 4
+	svcKeyECH           = 5
+	svcKeyIPv6Hint      = 6
+	svcKeyDoHPath       = 7
+)
+
+var svcKeyNames = map[uint16]string{
+	svcKeyMandatory:     "mandatory",
+	svcKeyALPN:          "alpn",
+	svcKeyNoDefaultALPN: "no-default-alpn",
+	svcKeyPort:          "port",
+	svcKeyIPv4Hint:      "ipv4hint",
+	svcKeyECH:           "ech",
+	svcKeyIPv6Hint:      "ipv6hint",
+	svcKeyDoHPath:       "dohpath",
+}
+
+func svcKeyName(key uint16) string {
+	if name, ok := svcKeyNames[key]; ok {
+		return name
+	}
+	return "key" + strconv.Itoa(int(key))
+}
+
+// An SVCBRecord is an SVCB or HTTPS record (RFC 9460). Priority 0 is alias
+// mode, where Target names the service and there are no parameters. ECH is
+// the base64 ECHConfigList. Other holds parameters pig does not know as
+// key=value.
+type SVCBRecord struct {
+	Name          string   `json:"name"`
+	Type          string   `json:"type"`
+	Priority      uint16   `json:"priority"`
+	Target        string   `json:"target"`
+	Mandatory     []string `json:"mandatory,omitempty"`
+	ALPN          []string `json:"alpn,omitempty"`
+	NoDefaultALPN bool     `json:"no_default_alpn,omitempty"`
+	Port          uint16   `json:"port,omitempty"`
+	IPv4Hint      []net.IP `json:"ipv4hint,omitempty"`
+	IPv6Hint      []net.IP `json:"ipv6hint,omitempty"`
+	ECH           string   `json:"ech,omitempty"`
+	DoHPath       string   `json:"dohpath,omitempty"`
+	Other         []string `json:"other,omitempty"`
+}
+
+// Alias reports whether the record is in alias mode.
+func (r *SVCBRecord) Alias() bool {
+	return r.Priority == 0
+}
+
+// unpackSVCB reads the RDATA of an SVCB or HTTPS record. The target name
+// is never compressed.
+func unpackSVCB(rdata []byte) (*SVCBRecord, error) {
+	if len(rdata) < 3 {
+		return nil, errMalformed
+	}
+	r := &SVCBRecord{Priority: binary.BigEndian.Uint16(rdata)}
+	target, off, err := unpackName(rdata, 2)
+	if err != nil {
+		return nil, err
+	}
+	r.Target = strings.ToLower(target)
+
+	for off < len(rdata) {
+		if off+4 > len(rdata) {
+			return nil, errMalformed
+		}
+		key := binary.BigEndian.Uint16(rdata[off:])
+		length := int(binary.BigEndian.Uint16(rdata[off+2:]))
+		off += 4
+		if off+length > len(rdata) {
+			return nil, errMalformed
+		}
+		value := rdata[off : off+length]
+		off += length
+
+		switch key {
+		case svcKeyMandatory:
+			if length%2 != 0 {
+				return nil, errMalformed
+			}
+			for i := 0; i < length; i += 2 {
+				r.Mandatory = append(r.Mandatory, svcKeyName(binary.BigEndian.Uint16(value[i:])))
+			}
+		case svcKeyALPN:
+			for i := 0; i < length; {
+				n := int(value[i])
+				if n == 0 || i+1+n > length {
+					return nil, errMalformed
+				}
+				r.ALPN = append(r.ALPN, string(value[i+1:i+1+n]))
+				i += 1 + n
+			}
+		case svcKeyNoDefaultALPN:
+			r.NoDefaultALPN = true
+		case svcKeyPort:
+			if length != 2 {
+				return nil, errMalformed
+			}
+			r.Port = binary.BigEndian.Uint16(value)
+		case svcKeyIPv4Hint, svcKeyIPv6Hint:
+			size := net.IPv4len
+			if key == svcKeyIPv6Hint {
+				size = net.IPv6len
+			}
+			if length == 0 || length%size != 0 {
+				return nil, errMalformed
+			}
+			for i := 0; i < length; i += size {
+				ip := net.IP(append([]byte(nil), value[i:i+size]...))
+				if key == svcKeyIPv4Hint {
+					r.IPv4Hint = append(r.IPv4Hint, ip)
+				} else {
+					r.IPv6Hint = append(r.IPv6Hint, ip)
+				}
+			}
+		case svcKeyECH:
+			r.ECH = base64.StdEncoding.EncodeToString(value)
+		case svcKeyDoHPath:
+			r.DoHPath = string(value)
+		default:
+			r.Other = append(r.Other, fmt.Sprintf("%s=%q", svcKeyName(key), value))
+		}
+	}
+	return r, nil
+}
+
+// String returns the record data in presentation form, such as
+// 1 . alpn=h2,h3 port=443 ipv4hint=192.0.2.1.
+func (r *SVCBRecord) String() string {
+	parts := []string{strconv.Itoa(int(r.Priority)), r.Target}
+	if len(r.Mandatory) > 0 {
+		parts = append(parts, "mandatory="+strings.Join(r.Mandatory, ","))
+	}
+	if len(r.ALPN) > 0 {
+		alpn := []string{}
+		for _, id := range r.ALPN {
+			alpn = append(alpn, strings.ReplaceAll(id, ",", "\\,"))
+		}
+		parts = append(parts, "alpn="+strings.Join(alpn, ","))
+	}
+	if r.NoDefaultALPN {
+		parts = append(parts, "no-default-alpn")
+	}
+	if r.Port != 0 {
+		parts = append(parts, "port="+strconv.Itoa(int(r.Port)))
+	}
+	for _, hint := range []struct {
+		key string
+		ips []net.IP
+	}{{"ipv4hint", r.IPv4Hint}, {"ipv6hint", r.IPv6Hint}} {
+		if len(hint.ips) > 0 {
+			ips := []string{}
+			for _, ip := range hint.ips {
+				ips = append(ips, ip.String())
+			}
+			parts = append(parts, hint.key+"="+strings.Join(ips, ","))
+		}
+	}
+	if r.ECH != "" {
+		parts = append(parts, "ech="+r.ECH)
+	}
+	if r.DoHPath != "" {
+		parts = append(parts, "dohpath="+r.DoHPath)
+	}
+	parts = append(parts, r.Other...)
+	return strings.Join(parts, " ")
+}
+
+// svcb looks up the SVCB or HTTPS records of name.
+func (s *Scanner) svcb(name string, qtype uint16) []SVCBRecord {
+	msg, err := s.query(name, qtype)
+	if err != nil {
+		return nil
+	}
+	records := []SVCBRecord{}
+	for _, rr := range msg.answers(name, qtype) {
+		if r, err := unpackSVCB(rr.Raw); err == nil {
+			r.Name, r.Type = rr.Name, typeString(qtype)
+			records = append(records, *r)
+		}
+	}
+	return records
+}
@@ -0,0 +1,151 @@
+package pig
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// svcbVectors are the wire format test vectors of RFC 9460 Appendix D,
+// with the presentation form String gives for each, plus one for ech.
+var svcbVectors = []struct {
+	name string
+	wire string
+	want string
+}{
+	{
+		"D.1 alias mode",
+		"0000 03666f6f076578616d706c6503636f6d00",
+		"0 foo.example.com.",
+	},
+	{
+		"D.2 figure 3, target is the owner",
+		"0001 00",
+		"1 .",
+	},
+	{
+		"D.2 figure 4, port",
+		"0010 03666f6f076578616d706c6503636f6d00 0003 0002 0035",
+		"16 foo.example.com. port=53",
+	},
+	{
+		"D.2 figure 5, generic key",
+		"0001 03666f6f076578616d706c6503636f6d00 029b 0005 68656c6c6f",
+		`1 foo.example.com. key667="hello"`,
+	},
+	{
+		"D.2 figure 6, generic key with an escaped value",
+		"0001 03666f6f076578616d706c6503636f6d00 029b 0009 68656c6c6fd2716f6f",
+		`1 foo.example.com. key667="hello\xd2qoo"`,
+	},
+	{
+		"D.2 figure 7, two ipv6hints",
+		"0001 03666f6f076578616d706c6503636f6d00 0006 0020 20010db8000000000000000000000001 20010db8000000000000000000530001",
+		"1 foo.example.com. ipv6hint=2001:db8::1,2001:db8::53:1",
+	},
+	{
+		"D.2 figure 8, ipv6hint with embedded IPv4",
+		"0001 076578616d706c6503636f6d00 0006 0010 20010db8012203440000 0000c0000221",
+		"1 example.com. ipv6hint=2001:db8:122:344::c000:221",
+	},
+	{
+		"D.2 figure 9, mandatory, alpn and ipv4hint",
+		"0010 03666f6f076578616d706c65036f726700 0000 0004 00010004 0001 0009 026832 0568332d3139 0004 0004 c0000201",
+		"16 foo.example.org. mandatory=alpn,ipv4hint alpn=h2,h3-19 ipv4hint=192.0.2.1",
+	},
+	{
+		"D.2 figure 10, alpn with escaped values",
+		"0010 03666f6f076578616d706c65036f726700 0001 000c 08665c6f6f2c626172 026832",
+		`16 foo.example.org. alpn=f\oo\,bar,h2`,
+	},
+	{
+		"ech and no-default-alpn",
+		"0001 00 0001 0003 026833 0002 0000 0005 0006 0004fe0d0000",
+		"1 . alpn=h3 no-default-alpn ech=AAT+DQAA",
+	},
+}
+
+func svcbWire(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestUnpackSVCB(t *testing.T) {
+	for _, tt := range svcbVectors {
+		r, err := unpackSVCB(svcbWire(t, tt.wire))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := r.String(); got != tt.want {
+			t.Errorf("%s:\ngot  %s\nwant %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUnpackSVCBFields(t *testing.T) {
+	r, err := unpackSVCB(svcbWire(t, svcbVectors[7].wire))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Alias() || r.Priority != 16 || r.Target != "foo.example.org." {
+		t.Errorf("priority %d target %s, want service mode 16 foo.example.org.", r.Priority, r.Target)
+	}
+	if !equalStrings(r.Mandatory, []string{"alpn", "ipv4hint"}) || !equalStrings(r.ALPN, []string{"h2", "h3-19"}) {
+		t.Errorf("mandatory %v alpn %v", r.Mandatory, r.ALPN)
+	}
+	if len(r.IPv4Hint) != 1 || r.IPv4Hint[0].String() != "192.0.2.1" {
+		t.Errorf("ipv4hint %v, want [192.0.2.1]", r.IPv4Hint)
+	}
+
+	r, err = unpackSVCB(svcbWire(t, svcbVectors[0].wire))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Alias() || r.Target != "foo.example.com." {
+		t.Errorf("alias %v target %s, want alias mode to foo.example.com.", r.Alias(), r.Target)
+	}
+}
+
+func TestUnpackSVCBMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		wire string
+	}{
+		{"too short", "0001"},
+		{"truncated target", "0001 03666f6f"},
+		{"truncated key", "0001 00 0003"},
+		{"value past the end", "0001 00 0003 0004 0035"},
+		{"port of one byte", "0001 00 0003 0001 35"},
+		{"empty alpn id", "0001 00 0001 0001 00"},
+		{"alpn id past the value", "0001 00 0001 0002 0568"},
+		{"odd mandatory", "0001 00 0000 0003 000100"},
+		{"short ipv4hint", "0001 00 0004 0003 c00002"},
+		{"empty ipv4hint", "0001 00 0004 0000"},
+		{"short ipv6hint", "0001 00 0006 0004 20010db8"},
+	}
+	for _, tt := range tests {
+		if r, err := unpackSVCB(svcbWire(t, tt.wire)); err == nil {
+			t.Errorf("%s: got %s, want an error", tt.name, r)
+		}
+	}
+}
+
+func TestHTTPSLookup(t *testing.T) {
+	srv := newTestServer(t, false,
+		"example.com 300 HTTPS "+strings.ReplaceAll(svcbVectors[7].wire, " ", ""),
+		"www.example.com 300 HTTPS "+strings.ReplaceAll(svcbVectors[0].wire, " ", ""),
+	)
+	s := newTestScanner(t, srv, Options{})
+	records := s.svcb("example.com", typeHTTPS)
+	if len(records) != 1 || records[0].Type != "HTTPS" || records[0].Name != "example.com." || records[0].String() != svcbVectors[7].want {
+		t.Errorf("svcb(example.com) = %+v", records)
+	}
+	if records := s.svcb("www.example.com", typeHTTPS); len(records) != 1 || !records[0].Alias() {
+		t.Errorf("svcb(www.example.com) = %+v, want one alias", records)
+	}
+}
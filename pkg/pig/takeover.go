@@ -0,0 +1,95 @@
+package pig
+
+import (
+	"io"
+	"strings"
+)
+
+type TakeoverResult struct {
+	Domain      string `json:"domain"`
+	Target      string `json:"target"`
+	Provider    string `json:"provider,omitempty"`
+	NXDOMAIN    bool   `json:"nxdomain,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+	Risk        string `json:"risk"`
+}
+
+// Takeover rates how easily the end of a CNAME chain could be claimed by
+// someone else. It returns nil when the chain has no CNAMEs. The target is
+// fetched over HTTP when ProbeHTTP is set and the profile is not Passive.
+func (s *Scanner) Takeover(chain *CNAMEChain) *TakeoverResult {
+	return s.takeover(chain, nil)
+}
+
+func (s *Scanner) takeover(chain *CNAMEChain, probes *probeLog) *TakeoverResult {
+	if chain == nil || len(chain.Hops) < 1 || chain.Self {
+		return nil
+	}
+
+	domain := strings.TrimSuffix(chain.Name, ".")
+	target := chain.Target()
+	res := &TakeoverResult{Domain: domain, Target: target}
+	var provider *takeoverProvider
+	for _, hop := range chain.Hops {
+		if p := s.services.takeoverProvider(hop.Target); p != nil {
+			provider = p
+			res.Provider = p.Provider
+		}
+	}
+	if msg, err := s.query(target, typeA); err == nil && msg.Rcode == rcodeNXDomain {
+		res.NXDOMAIN = true
+	}
+	if s.opts.ProbeHTTP && s.profile != Passive && !res.NXDOMAIN && provider != nil && len(provider.Fingerprints) > 0 {
+		for _, body := range s.fetchBodies(domain, probes) {
+			if res.Fingerprint = matchFingerprint(body, provider.Fingerprints); res.Fingerprint != "" {
+				break
+			}
+		}
+	}
+	res.Risk = takeoverRisk(res, provider)
+	return res
+}
+
+func takeoverRisk(res *TakeoverResult, provider *takeoverProvider) string {
+	switch {
+	case res.Fingerprint != "":
+		return "High"
+	case res.NXDOMAIN && provider != nil && provider.NXDOMAIN:
+		return "High"
+	case res.NXDOMAIN:
+		return "Medium"
+	case provider != nil:
+		return "Low"
+	default:
+		return "None"
+	}
+}
+
+// fetchBodies fetches host over HTTP and HTTPS. Providers may only show
+// their "unclaimed resource" page on one of them, so both are returned.
+func (s *Scanner) fetchBodies(host string, probes *probeLog) []string {
+	bodies := []string{}
+	for _, scheme := range []string{"http", "https"} {
+		probes.add("http", scheme+"://"+host+"/")
+		resp, err := s.client.Get(scheme + "://" + host + "/")
+		if err != nil {
+			continue
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512*1024))
+		resp.Body.Close()
+		bodies = append(bodies, string(body))
+	}
+	return bodies
+}
+
+func matchFingerprint(body string, fingerprints []string) string {
+	if body == "" {
+		return ""
+	}
+	for _, fp := range fingerprints {
+		if strings.Contains(body, fp) {
+			return fp
+		}
+	}
+	return ""
+}
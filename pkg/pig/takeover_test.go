@@ -0,0 +1,114 @@
+package pig
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// takeoverSite serves a page per host over HTTP and HTTPS, and routes every
+// connection of the scanner's HTTP client to it.
+type takeoverSite struct {
+	http, https map[string]string
+}
+
+func (site *takeoverSite) handler(w http.ResponseWriter, r *http.Request) {
+	pages := site.http
+	if r.TLS != nil {
+		pages = site.https
+	}
+	fmt.Fprint(w, pages[r.Host])
+}
+
+func (site *takeoverSite) attach(t *testing.T, s *Scanner) {
+	plain := httptest.NewServer(http.HandlerFunc(site.handler))
+	t.Cleanup(plain.Close)
+	secure := httptest.NewTLSServer(http.HandlerFunc(site.handler))
+	t.Cleanup(secure.Close)
+
+	var dialer net.Dialer
+	s.client = &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if _, port, _ := net.SplitHostPort(addr); port == "443" {
+				return dialer.DialContext(ctx, network, secure.Listener.Addr().String())
+			}
+			return dialer.DialContext(ctx, network, plain.Listener.Addr().String())
+		},
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}}
+}
+
+func takeoverChain(domain, target string) *CNAMEChain {
+	return &CNAMEChain{Name: fqdn(domain), Hops: []CNAMEHop{{Name: fqdn(domain), Target: fqdn(target)}}}
+}
+
+func TestTakeoverFingerprints(t *testing.T) {
+	db, err := loadServiceDB(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newTestServer(t, false)
+	s := newTestScanner(t, srv, Options{ProbeHTTP: true})
+	site := &takeoverSite{http: map[string]string{}, https: map[string]string{}}
+	site.attach(t, s)
+
+	for i, provider := range db.Takeover {
+		for j, fp := range provider.Fingerprints {
+			domain := fmt.Sprintf("site%d-%d.example.com", i, j)
+			target := "dangling." + provider.Suffixes[0]
+			srv.add(t, target+" 300 A 192.0.2.1")
+			site.http[domain] = "<html><body>" + fp + "</body></html>"
+
+			res := s.Takeover(takeoverChain(domain, target))
+			if res.Provider != provider.Provider || res.Fingerprint != fp || res.Risk != "High" {
+				t.Errorf("%s serving %q: got provider %q, fingerprint %q, risk %s", provider.Provider, fp, res.Provider, res.Fingerprint, res.Risk)
+			}
+		}
+	}
+}
+
+func TestTakeoverChecksHTTPS(t *testing.T) {
+	srv := newTestServer(t, false, "shop.example.myshopify.com 300 A 192.0.2.1")
+	s := newTestScanner(t, srv, Options{ProbeHTTP: true})
+	site := &takeoverSite{
+		http:  map[string]string{"shop.example.com": "<html>Moved</html>"},
+		https: map[string]string{"shop.example.com": "<html>Sorry, this shop is currently unavailable.</html>"},
+	}
+	site.attach(t, s)
+
+	res := s.Takeover(takeoverChain("shop.example.com", "shop.example.myshopify.com"))
+	if res.Fingerprint == "" || res.Risk != "High" {
+		t.Errorf("fingerprint served over HTTPS only: got fingerprint %q, risk %s", res.Fingerprint, res.Risk)
+	}
+}
+
+func TestTakeoverRisk(t *testing.T) {
+	srv := newTestServer(t, false,
+		"live.github.io 300 A 192.0.2.1",
+		"cdn.example.net 300 A 192.0.2.2",
+	)
+	s := newTestScanner(t, srv, Options{ProbeHTTP: true})
+	site := &takeoverSite{http: map[string]string{"live.example.com": "<html>Welcome</html>"}, https: map[string]string{}}
+	site.attach(t, s)
+
+	tests := []struct {
+		domain, target string
+		provider, risk string
+	}{
+		{"live.example.com", "live.github.io", "GitHub Pages", "Low"},
+		{"gone.example.com", "gone.herokuapp.com", "Heroku", "High"},
+		{"old.example.com", "old.example.org", "", "Medium"},
+		{"cdn.example.com", "cdn.example.net", "", "None"},
+		{"bucket.example.com", "bucket.s3-website-us-east-1.amazonaws.com", "Amazon S3", "Medium"},
+	}
+	for _, tt := range tests {
+		res := s.Takeover(takeoverChain(tt.domain, tt.target))
+		if res.Provider != tt.provider || res.Risk != tt.risk {
+			t.Errorf("%s -> %s: got provider %q, risk %s, want %q, %s", tt.domain, tt.target, res.Provider, res.Risk, tt.provider, tt.risk)
+		}
+	}
+}
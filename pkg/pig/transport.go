@@ -0,0 +1,174 @@
+package pig
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// A transport carries queries to the recursive resolver.
+type transport interface {
+	exchange(name string, qtype uint16, flags uint16, timeout time.Duration) (*dnsMsg, error)
+}
+
+// newTransport picks the transport for a resolver: DNS over HTTPS for
+// https:// URLs, DNS over TLS for tls://host[:port], and plain DNS over
+// UDP, falling back to TCP, for host[:port]. It returns the resolver with
+// its default port filled in.
+func newTransport(resolver string, config *tls.Config) (transport, string, error) {
+	scheme, rest, ok := strings.Cut(resolver, "://")
+	if !ok {
+		if _, _, err := net.SplitHostPort(resolver); err != nil {
+			resolver = net.JoinHostPort(resolver, "53")
+		}
+		return udpTransport{server: resolver}, resolver, nil
+	}
+
+	if config == nil {
+		config = &tls.Config{}
+	}
+	config = config.Clone()
+	switch strings.ToLower(scheme) {
+	case "tls":
+		addr := strings.TrimSuffix(rest, "/")
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = strings.Trim(addr, "[]")
+			addr = net.JoinHostPort(host, "853")
+		}
+		if config.ServerName == "" {
+			config.ServerName = host
+		}
+		if config.ClientSessionCache == nil {
+			config.ClientSessionCache = tls.NewLRUClientSessionCache(8)
+		}
+		return &tlsTransport{addr: addr, config: config}, "tls://" + addr, nil
+	case "https":
+		// An RFC 8484 URI template ending in {?dns} asks for GET requests.
+		template := strings.HasSuffix(resolver, "{?dns}")
+		endpoint := strings.TrimSuffix(resolver, "{?dns}")
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Host == "" {
+			return nil, "", fmt.Errorf("invalid DNS over HTTPS URL %q", resolver)
+		}
+		if u.Path == "" {
+			u.Path = "/dns-query"
+		}
+		t := &httpsTransport{
+			url: u.String(),
+			get: template,
+			client: &http.Client{Transport: &http.Transport{
+				Proxy:               http.ProxyFromEnvironment,
+				TLSClientConfig:     config,
+				ForceAttemptHTTP2:   true,
+				MaxIdleConnsPerHost: 4,
+				IdleConnTimeout:     90 * time.Second,
+			}},
+		}
+		if template {
+			return t, t.url + "{?dns}", nil
+		}
+		return t, t.url, nil
+	}
+	return nil, "", fmt.Errorf("unsupported resolver scheme %q, use https:// or tls://", scheme)
+}
+
+type udpTransport struct {
+	server string
+}
+
+func (t udpTransport) exchange(name string, qtype uint16, flags uint16, timeout time.Duration) (*dnsMsg, error) {
+	return dnsExchange(t.server, name, qtype, flags, timeout)
+}
+
+// tlsTransport sends each query over its own TLS connection to port 853
+// (RFC 7858), resuming TLS sessions to keep the handshakes short.
+type tlsTransport struct {
+	addr   string
+	config *tls.Config
+}
+
+func (t *tlsTransport) exchange(name string, qtype uint16, flags uint16, timeout time.Duration) (*dnsMsg, error) {
+	query, id, err := packQuery(name, qtype, flags)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &tls.Dialer{NetDialer: &net.Dialer{Timeout: timeout}, Config: t.config}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	conn, err := dialer.DialContext(ctx, "tcp", t.addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return streamExchange(conn, query, id, timeout)
+}
+
+// httpsTransport sends queries in wire format to a DNS over HTTPS server
+// (RFC 8484), by POST or, for URI templates, by GET. The message ID is 0 so
+// answers can be cached by HTTP caches.
+type httpsTransport struct {
+	url    string
+	get    bool
+	client *http.Client
+}
+
+func (t *httpsTransport) exchange(name string, qtype uint16, flags uint16, timeout time.Duration) (*dnsMsg, error) {
+	query, _, err := packQuery(name, qtype, flags)
+	if err != nil {
+		return nil, err
+	}
+	query[0], query[1] = 0, 0
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	var req *http.Request
+	if t.get {
+		sep := "?"
+		if strings.Contains(t.url, "?") {
+			sep = "&"
+		}
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, t.url+sep+"dns="+base64.RawURLEncoding.EncodeToString(query), nil)
+	} else {
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(query))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/dns-message")
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/dns-message")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DNS over HTTPS server returned %s", resp.Status)
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/dns-message") {
+		return nil, fmt.Errorf("DNS over HTTPS server returned %q instead of application/dns-message", ct)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 65535))
+	if err != nil {
+		return nil, err
+	}
+	msg, err := unpackMsg(body)
+	if err != nil {
+		return nil, err
+	}
+	if msg.ID != 0 {
+		return nil, errMalformed
+	}
+	return msg, nil
+}
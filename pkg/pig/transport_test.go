@@ -0,0 +1,242 @@
+package pig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var transportRecords = []string{
+	"example.com 300 A 192.0.2.1",
+	"example.com 300 TXT v=spf1 ip4:192.0.2.0/24 include:_spf.example.net include:_spf.example.org -all",
+	"example.com 300 TXT google-site-verification=" + strings.Repeat("x", 200),
+}
+
+// dohServer answers DNS over HTTPS requests from zone and records the
+// methods it was called with.
+type dohServer struct {
+	*httptest.Server
+	zone *testServer
+
+	mu      sync.Mutex
+	methods []string
+}
+
+func newDoHServer(t *testing.T, zone *testServer) *dohServer {
+	d := &dohServer{zone: zone}
+	d.Server = httptest.NewTLSServer(http.HandlerFunc(d.serve))
+	t.Cleanup(d.Close)
+	return d
+}
+
+func (d *dohServer) serve(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	d.methods = append(d.methods, r.Method)
+	d.mu.Unlock()
+
+	var query []byte
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		query, err = base64.RawURLEncoding.DecodeString(r.URL.Query().Get("dns"))
+	case http.MethodPost:
+		if r.Header.Get("Content-Type") != "application/dns-message" {
+			http.Error(w, "bad content type", http.StatusUnsupportedMediaType)
+			return
+		}
+		query, err = io.ReadAll(r.Body)
+	}
+	if err != nil || len(query) < 12 || binary.BigEndian.Uint16(query) != 0 {
+		http.Error(w, "bad query", http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/dns-message")
+	w.Write(d.zone.answer(query))
+}
+
+// tlsConfig trusts the certificate of the httptest TLS server.
+func tlsConfig(srv *httptest.Server) *tls.Config {
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	return &tls.Config{RootCAs: pool}
+}
+
+// newDoTServer answers DNS over TLS from zone with the certificate of srv.
+func newDoTServer(t *testing.T, zone *testServer, srv *httptest.Server) string {
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: srv.TLS.Certificates})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				var length [2]byte
+				if _, err := io.ReadFull(conn, length[:]); err != nil {
+					return
+				}
+				query := make([]byte, binary.BigEndian.Uint16(length[:]))
+				if _, err := io.ReadFull(conn, query); err != nil {
+					return
+				}
+				resp := zone.answer(query)
+				conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(resp))), resp...))
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestNewTransport(t *testing.T) {
+	tests := []struct {
+		resolver, want string
+	}{
+		{"192.0.2.53", "192.0.2.53:53"},
+		{"192.0.2.53:5353", "192.0.2.53:5353"},
+		{"[2001:db8::53]:53", "[2001:db8::53]:53"},
+		{"tls://dns.example", "tls://dns.example:853"},
+		{"tls://dns.example:8853", "tls://dns.example:8853"},
+		{"tls://[2001:db8::53]", "tls://[2001:db8::53]:853"},
+		{"https://dns.example", "https://dns.example/dns-query"},
+		{"https://dns.example/resolve", "https://dns.example/resolve"},
+		{"https://dns.example/dns-query{?dns}", "https://dns.example/dns-query{?dns}"},
+	}
+	for _, tt := range tests {
+		_, got, err := newTransport(tt.resolver, nil)
+		if err != nil || got != tt.want {
+			t.Errorf("newTransport(%q) = %q, %v, want %q", tt.resolver, got, err, tt.want)
+		}
+	}
+	for _, resolver := range []string{"ftp://dns.example", "https://", "quic://dns.example"} {
+		if _, _, err := newTransport(resolver, nil); err == nil {
+			t.Errorf("newTransport(%q) returned no error", resolver)
+		}
+	}
+}
+
+func TestTLSTransport(t *testing.T) {
+	zone := newTestServer(t, false, transportRecords...)
+	cert := httptest.NewTLSServer(http.NotFoundHandler())
+	defer cert.Close()
+	addr := newDoTServer(t, zone, cert)
+
+	s, err := NewScanner(Options{Resolver: "tls://" + addr, TLSConfig: tlsConfig(cert)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ips := s.lookupIP("example.com"); len(ips) != 1 || ips[0].String() != "192.0.2.1" {
+		t.Errorf("lookupIP over TLS = %v, want [192.0.2.1]", ips)
+	}
+	if txts := s.lookupTXT("example.com"); len(txts) != 2 {
+		t.Errorf("lookupTXT over TLS returned %d records, want 2", len(txts))
+	}
+
+	untrusted, err := NewScanner(Options{Resolver: "tls://" + addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := untrusted.query("example.com", typeA); err == nil {
+		t.Error("query to a DNS over TLS server with an untrusted certificate succeeded")
+	}
+}
+
+func TestHTTPSTransport(t *testing.T) {
+	zone := newTestServer(t, false, transportRecords...)
+	doh := newDoHServer(t, zone)
+
+	for _, tt := range []struct {
+		resolver, method string
+	}{
+		{doh.URL + "/dns-query", http.MethodPost},
+		{doh.URL + "/dns-query{?dns}", http.MethodGet},
+	} {
+		s, err := NewScanner(Options{Resolver: tt.resolver, TLSConfig: tlsConfig(doh.Server)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		doh.methods = nil
+		msg, err := s.query("example.com", typeA)
+		if err != nil {
+			t.Errorf("%s: %v", tt.resolver, err)
+			continue
+		}
+		if rrs := msg.answers("example.com", typeA); len(rrs) != 1 || rrs[0].Data != "192.0.2.1" {
+			t.Errorf("%s: answers = %v, want 192.0.2.1", tt.resolver, rrs)
+		}
+		if len(doh.methods) != 1 || doh.methods[0] != tt.method {
+			t.Errorf("%s: sent %v, want one %s", tt.resolver, doh.methods, tt.method)
+		}
+	}
+}
+
+func TestHTTPSTransportErrors(t *testing.T) {
+	tests := map[string]http.HandlerFunc{
+		"status": func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		},
+		"content type": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			io.WriteString(w, "<html></html>")
+		},
+	}
+	for name, handler := range tests {
+		srv := httptest.NewTLSServer(handler)
+		s, err := NewScanner(Options{Resolver: srv.URL, TLSConfig: tlsConfig(srv)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.query("example.com", typeA); err == nil {
+			t.Errorf("%s: query returned no error", name)
+		}
+		srv.Close()
+	}
+}
+
+func TestAmplificationUsesTransport(t *testing.T) {
+	zone := newTestServer(t, false, transportRecords...)
+	doh := newDoHServer(t, zone)
+	s, err := NewScanner(Options{Resolver: doh.URL, TLSConfig: tlsConfig(doh.Server), Profile: Active})
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := &LookupLog{}
+	checks := s.WithLog(log).Amplification("example.com")
+
+	if len(doh.methods) != 4 || zone.count() != 4 {
+		t.Errorf("amplification sent %d DNS over HTTPS requests, the zone answered %d queries, want 4 and 4", len(doh.methods), zone.count())
+	}
+	if len(log.Lookups()) != 4 {
+		t.Errorf("amplification logged %d lookups, want 4", len(log.Lookups()))
+	}
+	for _, check := range checks {
+		if check.Type == "TXT" && (check.ResponseSize <= check.QuerySize || check.Factor <= 1) {
+			t.Errorf("TXT check = %+v, want a response larger than the query", check)
+		}
+	}
+	if len(checks) != 4 {
+		t.Errorf("got %d checks, want 4", len(checks))
+	}
+}
+
+func TestUDPTransport(t *testing.T) {
+	zone := newTestServer(t, false, transportRecords...)
+	s := newTestScanner(t, zone, Options{})
+	if ips := s.lookupIP("example.com"); len(ips) != 1 {
+		t.Errorf("lookupIP = %v, want one address", ips)
+	}
+	if msg, err := s.query("missing.example.com", typeA); err != nil || msg.Rcode != rcodeNXDomain {
+		t.Errorf("query for a missing name = %v, %v, want NXDOMAIN", msg, err)
+	}
+}
@@ -0,0 +1,304 @@
+package pig
+
+import (
+	"errors"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DiffKind says how an RRset served by a nameserver differs from the zone
+// file.
+type DiffKind string
+
+const (
+	// DiffMissing is an RRset of the zone file the server does not serve.
+	DiffMissing DiffKind = "missing"
+	// DiffExtra is an RRset the server serves at a name of the zone file
+	// that the file does not have.
+	DiffExtra DiffKind = "extra"
+	// DiffMismatch is an RRset whose records differ.
+	DiffMismatch DiffKind = "mismatch"
+	// DiffTTL is an RRset with the same records and a different TTL.
+	DiffTTL DiffKind = "ttl"
+	// DiffError is an RRset the server gave no usable answer for.
+	DiffError DiffKind = "error"
+)
+
+// verifyTypes are the types whose data pig decodes, so an answer can be
+// compared with the zone file. The others are counted as skipped.
+var verifyTypes = []uint16{typeSOA, typeNS, typeA, typeAAAA, typeCNAME, typeMX, typeTXT, typeSRV, typeCAA, typePTR}
+
+// An RRsetDiff is one difference between a server and the zone file. File
+// and Line locate the RRset in the zone file, unless it is extra.
+type RRsetDiff struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Kind        DiffKind `json:"kind"`
+	File        string   `json:"file,omitempty"`
+	Line        int      `json:"line,omitempty"`
+	Expected    []string `json:"expected,omitempty"`
+	Actual      []string `json:"actual,omitempty"`
+	ExpectedTTL uint32   `json:"expected_ttl,omitempty"`
+	ActualTTL   uint32   `json:"actual_ttl,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+// A ServerVerification compares what one address of a nameserver serves
+// with the zone file. Error is set when the server could not be checked at
+// all, because it has no address, does not answer or is not authoritative.
+type ServerVerification struct {
+	Server  string      `json:"server"`
+	Address string      `json:"address,omitempty"`
+	Error   string      `json:"error,omitempty"`
+	Checked int         `json:"checked"`
+	Matched int         `json:"matched"`
+	Diffs   []RRsetDiff `json:"diffs"`
+}
+
+// A Verification compares live DNS with a zone file. Skipped counts the
+// RRsets that were not compared: those of types pig does not decode and
+// those below delegations, which the servers answer with referrals.
+type Verification struct {
+	Domain  string               `json:"domain"`
+	File    string               `json:"file"`
+	Time    time.Time            `json:"time"`
+	Servers []ServerVerification `json:"servers"`
+	Skipped int                  `json:"skipped"`
+	Probes  []Probe              `json:"probes"`
+	Lookups []Lookup             `json:"lookups,omitempty"`
+}
+
+// Differences counts the diffs and failed servers of all servers.
+func (v *Verification) Differences() int {
+	n := 0
+	for _, server := range v.Servers {
+		n += len(server.Diffs)
+		if server.Error != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// Verify asks each authoritative nameserver of domain directly for every
+// RRset of the zone, and for the other types pig decodes at each name of
+// the zone, and compares the answers with the zone file. servers are
+// host or host:port nameservers to check; when there are none, the NS
+// records the resolver returns for domain and those at the apex of the
+// zone are used, each at every address. Names that are only on the
+// servers cannot be found without a zone transfer. Verify sends queries
+// straight to the nameservers, so the Passive profile refuses it.
+func (s *Scanner) Verify(domain string, z *Zone, servers []string) (*Verification, error) {
+	if s.profile == Passive {
+		return nil, errors.New("verify queries the nameservers directly, which the passive profile does not allow")
+	}
+	log := &LookupLog{parent: s.log}
+	s = s.WithLog(log)
+	probes := &probeLog{}
+	origin := fqdn(domain)
+	v := &Verification{Domain: strings.TrimSuffix(origin, "."), File: z.File, Time: time.Now().UTC(), Servers: []ServerVerification{}}
+
+	// sets holds the RRsets of the zone by name and type, and lint its
+	// records by name, in the order the names first appear.
+	sets, names := map[string][]ZoneRecord{}, []string{}
+	lint := &linter{zone: &Zone{Origin: origin}, names: map[string][]ZoneRecord{}}
+	for _, rec := range z.Records {
+		if rec.Name != origin && !strings.HasSuffix(rec.Name, "."+origin) {
+			continue
+		}
+		if len(lint.names[rec.Name]) == 0 {
+			names = append(names, rec.Name)
+		}
+		lint.names[rec.Name] = append(lint.names[rec.Name], rec)
+		sets[rec.Name+" "+rec.Type] = append(sets[rec.Name+" "+rec.Type], rec)
+	}
+
+	// Names below a delegation are answered with referrals, and the NS
+	// records at the cut are not authoritative data of the zone.
+	checked := []string{}
+	for _, name := range names {
+		if name != origin && lint.delegated(name) {
+			v.Skipped += len(lint.names[name])
+			continue
+		}
+		checked = append(checked, name)
+		for _, rec := range lint.names[name] {
+			if !verifiable(rec.Type) {
+				v.Skipped++
+			}
+		}
+	}
+
+	for _, target := range s.verifyServers(origin, lint.find(origin, "NS"), servers) {
+		v.Servers = append(v.Servers, s.verifyServer(target, origin, checked, sets, probes))
+	}
+	v.Probes = probes.list()
+	v.Lookups = log.Lookups()
+	return v, nil
+}
+
+func verifiable(rtype string) bool {
+	for _, t := range verifyTypes {
+		if typeString(t) == rtype {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyServers lists the servers to check with their addresses. Servers
+// without an address are listed with an error.
+func (s *Scanner) verifyServers(origin string, zoneNS []ZoneRecord, servers []string) []ServerVerification {
+	targets := []ServerVerification{}
+	if len(servers) > 0 {
+		for _, server := range servers {
+			host, port, err := net.SplitHostPort(server)
+			if err != nil {
+				host, port = server, nameserverPort
+			}
+			targets = append(targets, s.serverAddresses(host, port)...)
+		}
+		return targets
+	}
+
+	hosts := []string{}
+	if msg, err := s.query(origin, typeNS); err == nil {
+		for _, rr := range msg.answers(origin, typeNS) {
+			hosts = append(hosts, strings.ToLower(rr.Data))
+		}
+	}
+	for _, rec := range zoneNS {
+		if !contains(hosts, rec.Data) {
+			hosts = append(hosts, rec.Data)
+		}
+	}
+	for _, host := range hosts {
+		targets = append(targets, s.serverAddresses(host, nameserverPort)...)
+	}
+	return targets
+}
+
+func (s *Scanner) serverAddresses(host, port string) []ServerVerification {
+	if ip := net.ParseIP(host); ip != nil {
+		return []ServerVerification{{Server: host, Address: net.JoinHostPort(ip.String(), port)}}
+	}
+	targets := []ServerVerification{}
+	for _, ip := range s.lookupIP(host) {
+		targets = append(targets, ServerVerification{Server: host, Address: net.JoinHostPort(ip.String(), port)})
+	}
+	if len(targets) == 0 {
+		targets = append(targets, ServerVerification{Server: host, Error: "no A or AAAA records"})
+	}
+	return targets
+}
+
+// verifyServer compares the answers of one server with the zone. A server
+// that does not answer the SOA query authoritatively is not checked any
+// further.
+func (s *Scanner) verifyServer(v ServerVerification, origin string, names []string, sets map[string][]ZoneRecord, probes *probeLog) ServerVerification {
+	v.Diffs = []RRsetDiff{}
+	if v.Error != "" {
+		return v
+	}
+	probes.add("query", v.Address)
+	soa, err := s.exchangeWith(v.Address, origin, typeSOA, 0)
+	switch {
+	case err != nil:
+		v.Error = err.Error()
+		return v
+	case soa.Rcode != rcodeSuccess:
+		v.Error = "SOA query returned " + rcodeString(soa.Rcode)
+		return v
+	case !soa.Authoritative:
+		v.Error = "not authoritative for " + strings.TrimSuffix(origin, ".")
+		return v
+	}
+
+	for _, name := range names {
+		types := verifyTypes
+		// A name with a CNAME has no other data, and asking for other
+		// types would follow the alias.
+		if len(sets[name+" CNAME"]) > 0 {
+			types = []uint16{typeCNAME}
+		}
+		for _, t := range types {
+			rtype := typeString(t)
+			expected := sets[name+" "+rtype]
+			if t == typeSOA && name != origin && len(expected) == 0 {
+				continue
+			}
+			msg := soa
+			if name != origin || t != typeSOA {
+				probes.add("query", v.Address)
+				if msg, err = s.exchangeWith(v.Address, name, t, 0); err != nil || (msg.Rcode != rcodeSuccess && msg.Rcode != rcodeNXDomain) {
+					if len(expected) > 0 {
+						v.Checked++
+						diff := rrsetDiff(name, rtype, expected, nil, DiffError)
+						if err != nil {
+							diff.Error = err.Error()
+						} else {
+							diff.Error = rcodeString(msg.Rcode)
+						}
+						v.Diffs = append(v.Diffs, diff)
+					}
+					continue
+				}
+			}
+			if diff := compareRRset(name, rtype, expected, msg.answers(name, t)); diff != nil {
+				if len(expected) > 0 {
+					v.Checked++
+				}
+				v.Diffs = append(v.Diffs, *diff)
+			} else if len(expected) > 0 {
+				v.Checked++
+				v.Matched++
+			}
+		}
+	}
+	return v
+}
+
+// compareRRset returns how the answer differs from the expected records,
+// or nil when it does not.
+func compareRRset(name, rtype string, expected []ZoneRecord, answer []dnsRR) *RRsetDiff {
+	if len(expected) == 0 && len(answer) == 0 {
+		return nil
+	}
+	actual := []string{}
+	for _, rr := range answer {
+		actual = append(actual, rr.Data)
+	}
+	switch {
+	case len(answer) == 0:
+		diff := rrsetDiff(name, rtype, expected, nil, DiffMissing)
+		return &diff
+	case len(expected) == 0:
+		diff := rrsetDiff(name, rtype, nil, actual, DiffExtra)
+		diff.ActualTTL = answer[0].TTL
+		return &diff
+	}
+	diff := rrsetDiff(name, rtype, expected, actual, DiffMismatch)
+	diff.ActualTTL = answer[0].TTL
+	if strings.Join(diff.Expected, "\n") == strings.Join(diff.Actual, "\n") {
+		if diff.ExpectedTTL == diff.ActualTTL {
+			return nil
+		}
+		diff.Kind = DiffTTL
+	}
+	return &diff
+}
+
+func rrsetDiff(name, rtype string, expected []ZoneRecord, actual []string, kind DiffKind) RRsetDiff {
+	diff := RRsetDiff{Name: name, Type: rtype, Kind: kind, Actual: actual}
+	for _, rec := range expected {
+		diff.Expected = append(diff.Expected, rec.Data)
+	}
+	if len(expected) > 0 {
+		diff.File, diff.Line, diff.ExpectedTTL = expected[0].File, expected[0].Line, expected[0].TTL
+	}
+	sort.Strings(diff.Expected)
+	sort.Strings(diff.Actual)
+	return diff
+}
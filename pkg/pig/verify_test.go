@@ -0,0 +1,151 @@
+package pig
+
+import (
+	"strings"
+	"testing"
+)
+
+const verifyTestZone = `$ORIGIN example.com.
+$TTL 300
+@ SOA ns1 hostmaster 2024010101 7200 3600 1209600 300
+  NS ns1
+  MX 010 mail
+  CAA 000 issue "letsencrypt.org"
+ns1 A 192.0.2.1
+mail A 192.0.2.2
+www A 192.0.2.3
+ftp A 192.0.2.4
+_sip._tcp SRV 010 060 05060 sip
+sip 600 A 192.0.2.5
+alias CNAME www
+key TLSA 3 1 1 abcdef
+sub NS ns.sub
+ns.sub A 192.0.2.6
+`
+
+func TestVerify(t *testing.T) {
+	auth := newTestServer(t, true,
+		"example.com 300 SOA ns1.example.com hostmaster.example.com 2024010101 7200 3600 1209600 300",
+		"example.com 300 NS ns1.example.com",
+		"example.com 300 MX 10 mail.example.com",
+		`example.com 300 CAA 0 issue "letsencrypt.org"`,
+		"ns1.example.com 300 A 192.0.2.1",
+		"mail.example.com 300 A 192.0.2.2",
+		"www.example.com 300 A 192.0.2.9",
+		"www.example.com 300 TXT unexpected",
+		"_sip._tcp.example.com 300 SRV 10 60 5060 sip.example.com",
+		"sip.example.com 300 A 192.0.2.5",
+		"alias.example.com 300 CNAME www.example.com",
+	)
+	z, err := ParseZoneFile(writeZone(t, "db.example.com", verifyTestZone), "")
+	if err != nil || len(z.Errors) > 0 {
+		t.Fatalf("parsing the zone: %v %v", err, z.Errors)
+	}
+
+	v, err := newTestScanner(t, auth, Options{}).Verify("example.com", z, []string{auth.addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(v.Servers) != 1 {
+		t.Fatalf("checked %d servers, want 1", len(v.Servers))
+	}
+	server := v.Servers[0]
+	if server.Error != "" || server.Address != auth.addr {
+		t.Fatalf("server %+v, want %s checked", server, auth.addr)
+	}
+
+	got := []string{}
+	for _, d := range server.Diffs {
+		got = append(got, d.Name+" "+d.Type+" "+string(d.Kind))
+	}
+	want := []string{
+		"www.example.com. A mismatch",
+		"www.example.com. TXT extra",
+		"ftp.example.com. A missing",
+		"sip.example.com. A ttl",
+	}
+	if !equalStrings(got, want) {
+		t.Errorf("diffs %v, want %v", got, want)
+	}
+	// SOA, NS, MX and CAA at the apex, ns1, mail, SRV and alias match;
+	// www, ftp and sip do not. The extra TXT RRset is not in the zone.
+	if server.Checked != 11 || server.Matched != 8 {
+		t.Errorf("checked %d and matched %d RRsets, want 11 and 8", server.Checked, server.Matched)
+	}
+	// The TLSA record, and the NS and glue below the delegation.
+	if v.Skipped != 3 {
+		t.Errorf("skipped %d records, want 3", v.Skipped)
+	}
+	if v.Differences() != 4 {
+		t.Errorf("Differences() = %d, want 4", v.Differences())
+	}
+
+	auth.mu.Lock()
+	defer auth.mu.Unlock()
+	for _, query := range auth.queries {
+		if strings.HasSuffix(strings.Fields(query)[0], "sub.example.com.") {
+			t.Errorf("queried %s below the delegation", query)
+		}
+		if strings.HasPrefix(query, "alias.example.com. ") && query != "alias.example.com. CNAME" {
+			t.Errorf("queried %s at a CNAME", query)
+		}
+	}
+}
+
+func TestVerifyServerErrors(t *testing.T) {
+	records := []string{
+		"example.com 300 SOA ns1.example.com hostmaster.example.com 1 7200 3600 1209600 300",
+		"example.com 300 NS ns1.example.com",
+	}
+	resolver := newTestServer(t, false, records...)
+	failing := newTestServer(t, true, records...)
+	failing.fail("example.com", "SOA", rcodeRefused)
+	z, err := ParseZoneFile(writeZone(t, "db", "$ORIGIN example.com.\n$TTL 300\n@ NS ns1\n"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := newTestScanner(t, resolver, Options{}).Verify("example.com", z, []string{resolver.addr, failing.addr, "ns.missing.example"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantErrors := []string{"not authoritative", "REFUSED", "no A or AAAA records"}
+	if len(v.Servers) != len(wantErrors) {
+		t.Fatalf("servers %+v, want %d", v.Servers, len(wantErrors))
+	}
+	for i, want := range wantErrors {
+		if !strings.Contains(v.Servers[i].Error, want) {
+			t.Errorf("server %s error %q, want %q", v.Servers[i].Server, v.Servers[i].Error, want)
+		}
+	}
+	if v.Differences() != 3 {
+		t.Errorf("Differences() = %d, want 3", v.Differences())
+	}
+
+	if _, err := newTestScanner(t, resolver, Options{Profile: Passive}).Verify("example.com", z, nil); err == nil {
+		t.Error("Verify with the passive profile returned no error")
+	}
+}
+
+func TestVerifyZoneNS(t *testing.T) {
+	auth := newTestServer(t, true,
+		"example.com 300 SOA ns1.example.com hostmaster.example.com 1 7200 3600 1209600 300",
+		"example.com 300 NS ns1.example.com",
+		"ns1.example.com 300 A 127.0.0.1",
+	)
+	useNameserver(t, auth)
+	z, err := ParseZoneFile(writeZone(t, "db", "$ORIGIN example.com.\n$TTL 300\n@ SOA ns1 hostmaster 1 7200 3600 1209600 300\n  NS ns1\nns1 A 127.0.0.1\n"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := newTestScanner(t, auth, Options{}).Verify("example.com", z, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(v.Servers) != 1 || v.Servers[0].Server != "ns1.example.com." || v.Servers[0].Address != auth.addr {
+		t.Fatalf("servers %+v, want ns1.example.com. at %s", v.Servers, auth.addr)
+	}
+	if v.Differences() != 0 || v.Servers[0].Matched != 3 {
+		t.Errorf("server %+v, want three matching RRsets", v.Servers[0])
+	}
+}
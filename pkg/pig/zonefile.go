@@ -0,0 +1,543 @@
+package pig
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// maxIncludeDepth bounds nested $INCLUDE directives.
+const maxIncludeDepth = 10
+
+// A ZoneRecord is a record read from a master file, with where it starts.
+type ZoneRecord struct {
+	Record
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+// A ZoneError is a line of a master file that could not be read.
+type ZoneError struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (e ZoneError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Message)
+}
+
+// A Zone is the content of an RFC 1035 master file and the files it
+// includes. Names are lower case and fully qualified.
+type Zone struct {
+	File    string       `json:"file"`
+	Origin  string       `json:"origin"`
+	Records []ZoneRecord `json:"records"`
+	Errors  []ZoneError  `json:"errors,omitempty"`
+}
+
+// zoneTypes are the record types a master file may use besides the ones
+// pig queries, and TYPEnnn.
+var zoneTypes = map[string]bool{
+	"SPF": true, "DNAME": true, "NAPTR": true, "SSHFP": true, "TLSA": true, "SMIMEA": true,
+	"HINFO": true, "LOC": true, "RP": true, "NSEC": true, "NSEC3": true, "NSEC3PARAM": true,
+	"CDS": true, "CDNSKEY": true, "URI": true, "OPENPGPKEY": true, "CERT": true, "KX": true,
+	"AFSDB": true, "APL": true, "DHCID": true, "ZONEMD": true, "CSYNC": true,
+}
+
+// ParseZoneFile reads a BIND-style master file with $ORIGIN, $TTL and
+// $INCLUDE directives and parenthesised multi-line records. Included files
+// are found relative to the file that includes them. origin is used until
+// the file sets its own; it may be empty when the file starts with
+// $ORIGIN or only uses absolute names. Lines that cannot be read are
+// listed in Errors and skipped. The error is only set when path itself
+// cannot be read.
+func ParseZoneFile(path, origin string) (*Zone, error) {
+	z := &Zone{File: path, Origin: fqdnOrEmpty(origin), Records: []ZoneRecord{}}
+	p := &zoneParser{zone: z, origin: z.Origin}
+	if err := p.parseFile(path, 0); err != nil {
+		return nil, err
+	}
+	if z.Origin == "" {
+		for _, rec := range z.Records {
+			if rec.Type == "SOA" {
+				z.Origin = rec.Name
+				break
+			}
+		}
+	}
+	return z, nil
+}
+
+func fqdnOrEmpty(name string) string {
+	if name == "" {
+		return ""
+	}
+	return fqdn(name)
+}
+
+type zoneParser struct {
+	zone       *Zone
+	origin     string
+	defaultTTL *uint32
+	lastTTL    *uint32
+	lastName   string
+}
+
+// zoneToken is a word of a master file entry. Quoted strings keep their
+// spaces and lose their quotes.
+type zoneToken struct {
+	text   string
+	quoted bool
+}
+
+func (p *zoneParser) errorf(file string, line int, format string, args ...interface{}) {
+	p.zone.Errors = append(p.zone.Errors, ZoneError{File: file, Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+func (p *zoneParser) parseFile(path string, depth int) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var tokens []zoneToken
+	start, lineNo, parens := 0, 0, 0
+	indented := false
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if parens == 0 {
+			start = lineNo
+			indented = len(line) > 0 && (line[0] == ' ' || line[0] == '\t')
+		}
+		var msg string
+		tokens, parens, msg = tokenizeZoneLine(line, tokens, parens)
+		if msg != "" {
+			p.errorf(path, lineNo, "%s", msg)
+			tokens, parens = nil, 0
+			continue
+		}
+		if parens > 0 {
+			continue
+		}
+		if len(tokens) > 0 {
+			p.entry(path, start, indented, tokens, depth)
+		}
+		tokens = nil
+	}
+	if parens > 0 {
+		p.errorf(path, start, "unclosed parenthesis")
+	}
+	return scanner.Err()
+}
+
+// tokenizeZoneLine appends the words of one line to tokens and returns the
+// parenthesis depth after it.
+func tokenizeZoneLine(line string, tokens []zoneToken, parens int) ([]zoneToken, int, string) {
+	var word strings.Builder
+	inWord, quoted := false, false
+	flush := func() {
+		if inWord {
+			tokens = append(tokens, zoneToken{text: word.String(), quoted: quoted})
+		}
+		word.Reset()
+		inWord, quoted = false, false
+	}
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '"':
+			flush()
+			end := i + 1
+			for ; end < len(line) && line[end] != '"'; end++ {
+				if line[end] == '\\' && end+1 < len(line) {
+					end++
+				}
+			}
+			if end >= len(line) {
+				return tokens, parens, "unterminated quoted string"
+			}
+			text, err := unescapeZoneString(line[i+1 : end])
+			if err != nil {
+				return tokens, parens, err.Error()
+			}
+			tokens = append(tokens, zoneToken{text: text, quoted: true})
+			i = end
+		case c == ';':
+			flush()
+			return tokens, parens, ""
+		case c == '(':
+			flush()
+			parens++
+		case c == ')':
+			flush()
+			if parens == 0 {
+				return tokens, parens, "unbalanced closing parenthesis"
+			}
+			parens--
+		case c == ' ' || c == '\t' || c == '\r':
+			flush()
+		case c == '\\' && i+1 < len(line):
+			word.WriteByte(c)
+			word.WriteByte(line[i+1])
+			inWord = true
+			i++
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	flush()
+	return tokens, parens, ""
+}
+
+// unescapeZoneString resolves \X and \DDD escapes in a quoted string.
+func unescapeZoneString(s string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 >= len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+3 < len(s) && isDigits(s[i+1:i+4]) {
+			n, _ := strconv.Atoi(s[i+1 : i+4])
+			if n > 255 {
+				return "", fmt.Errorf("invalid escape \\%s", s[i+1:i+4])
+			}
+			b.WriteByte(byte(n))
+			i += 3
+			continue
+		}
+		b.WriteByte(s[i+1])
+		i++
+	}
+	return b.String(), nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// entry handles one directive or record. Records that start with
+// whitespace belong to the previous owner name.
+func (p *zoneParser) entry(file string, line int, indented bool, tokens []zoneToken, depth int) {
+	first := tokens[0].text
+	if !indented && !tokens[0].quoted && strings.HasPrefix(first, "$") {
+		p.directive(file, line, tokens, depth)
+		return
+	}
+
+	name := p.lastName
+	if !indented {
+		var err error
+		if name, err = p.absolute(first); err != nil {
+			p.errorf(file, line, "%v", err)
+			return
+		}
+		tokens = tokens[1:]
+	}
+	if name == "" {
+		p.errorf(file, line, "record has no owner name")
+		return
+	}
+	p.lastName = name
+
+	var ttl *uint32
+	rtype := ""
+	for len(tokens) > 0 && rtype == "" {
+		word := strings.ToUpper(tokens[0].text)
+		switch {
+		case word == "IN" || word == "CH" || word == "HS" || word == "CS":
+			if word != "IN" {
+				p.errorf(file, line, "class %s is not supported", word)
+				return
+			}
+		case ttl == nil && isTTL(word):
+			v, err := parseTTL(word)
+			if err != nil {
+				p.errorf(file, line, "%v", err)
+				return
+			}
+			ttl = &v
+		default:
+			rtype = word
+		}
+		tokens = tokens[1:]
+	}
+	if rtype == "" {
+		p.errorf(file, line, "record has no type")
+		return
+	}
+	if !knownZoneType(rtype) {
+		p.errorf(file, line, "unknown record type %s", rtype)
+		return
+	}
+
+	data, err := p.rdata(rtype, tokens)
+	if err != nil {
+		p.errorf(file, line, "%s record: %v", rtype, err)
+		return
+	}
+
+	switch {
+	case ttl != nil:
+	case p.defaultTTL != nil:
+		ttl = p.defaultTTL
+	case p.lastTTL != nil:
+		ttl = p.lastTTL
+	case rtype == "SOA":
+		fields := strings.Fields(data)
+		v, _ := strconv.ParseUint(fields[len(fields)-1], 10, 32)
+		min := uint32(v)
+		ttl = &min
+	default:
+		p.errorf(file, line, "record has no TTL and no $TTL is set")
+		return
+	}
+	p.lastTTL = ttl
+	p.zone.Records = append(p.zone.Records, ZoneRecord{
+		Record: Record{Name: name, Type: rtype, TTL: *ttl, Data: data},
+		File:   file,
+		Line:   line,
+	})
+}
+
+func (p *zoneParser) directive(file string, line int, tokens []zoneToken, depth int) {
+	args := tokens[1:]
+	switch strings.ToUpper(tokens[0].text) {
+	case "$ORIGIN":
+		if len(args) != 1 || !strings.HasSuffix(args[0].text, ".") {
+			p.errorf(file, line, "$ORIGIN needs one absolute name")
+			return
+		}
+		p.origin = strings.ToLower(args[0].text)
+		if p.zone.Origin == "" {
+			p.zone.Origin = p.origin
+		}
+	case "$TTL":
+		if len(args) != 1 {
+			p.errorf(file, line, "$TTL needs one value")
+			return
+		}
+		v, err := parseTTL(args[0].text)
+		if err != nil {
+			p.errorf(file, line, "%v", err)
+			return
+		}
+		p.defaultTTL = &v
+	case "$INCLUDE":
+		if len(args) < 1 || len(args) > 2 {
+			p.errorf(file, line, "$INCLUDE needs a file name and an optional origin")
+			return
+		}
+		if depth >= maxIncludeDepth {
+			p.errorf(file, line, "$INCLUDE nested more than %d deep", maxIncludeDepth)
+			return
+		}
+		path := args[0].text
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(file), path)
+		}
+		// The included file gets its own origin and owner name, and the
+		// including file carries on with its own afterwards.
+		saved := *p
+		if len(args) == 2 {
+			origin, err := p.absolute(args[1].text)
+			if err != nil {
+				p.errorf(file, line, "%v", err)
+				return
+			}
+			p.origin = origin
+		}
+		if err := p.parseFile(path, depth+1); err != nil {
+			p.errorf(file, line, "$INCLUDE: %v", err)
+		}
+		p.origin, p.lastName = saved.origin, saved.lastName
+	case "$GENERATE":
+		p.errorf(file, line, "$GENERATE is not supported")
+	default:
+		p.errorf(file, line, "unknown directive %s", tokens[0].text)
+	}
+}
+
+// absolute qualifies a name with the current origin.
+func (p *zoneParser) absolute(name string) (string, error) {
+	switch {
+	case name == "@":
+		if p.origin == "" {
+			return "", fmt.Errorf("@ used before $ORIGIN is set")
+		}
+		return p.origin, nil
+	case strings.HasSuffix(name, "."):
+		return strings.ToLower(name), nil
+	case p.origin == "":
+		return "", fmt.Errorf("relative name %s used before $ORIGIN is set", name)
+	}
+	return strings.ToLower(name) + "." + p.origin, nil
+}
+
+func knownZoneType(rtype string) bool {
+	for _, name := range typeNames {
+		if name == rtype && rtype != "OPT" && rtype != "ANY" {
+			return true
+		}
+	}
+	return zoneTypes[rtype] || (strings.HasPrefix(rtype, "TYPE") && isDigits(rtype[4:]))
+}
+
+// isTTL reports whether a word looks like a TTL rather than a type.
+func isTTL(word string) bool {
+	return word != "" && word[0] >= '0' && word[0] <= '9'
+}
+
+// parseTTL reads a TTL in seconds or with BIND's s, m, h, d and w units,
+// such as 1h30m.
+func parseTTL(word string) (uint32, error) {
+	if isDigits(word) {
+		v, err := strconv.ParseUint(word, 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid TTL %s", word)
+		}
+		return uint32(v), nil
+	}
+	units := map[byte]uint64{'S': 1, 'M': 60, 'H': 3600, 'D': 86400, 'W': 604800}
+	total, n := uint64(0), ""
+	for i := 0; i < len(word); i++ {
+		c := word[i] &^ 0x20
+		if word[i] >= '0' && word[i] <= '9' {
+			n += string(word[i])
+			continue
+		}
+		unit, ok := units[c]
+		if !ok || n == "" {
+			return 0, fmt.Errorf("invalid TTL %s", word)
+		}
+		v, _ := strconv.ParseUint(n, 10, 32)
+		total += v * unit
+		n = ""
+	}
+	if n != "" || total > 1<<31-1 {
+		return 0, fmt.Errorf("invalid TTL %s", word)
+	}
+	return uint32(total), nil
+}
+
+// rdata checks the data of a record and puts it in the form pig shows for
+// records it looks up, with names fully qualified.
+func (p *zoneParser) rdata(rtype string, tokens []zoneToken) (string, error) {
+	words := []string{}
+	for _, t := range tokens {
+		words = append(words, t.text)
+	}
+	need := func(n int) error {
+		if len(words) != n {
+			return fmt.Errorf("expected %d fields, got %d", n, len(words))
+		}
+		return nil
+	}
+	// number checks a numeric field and writes it the way answers show
+	// it, so that 010 in the file matches 10 from a server.
+	number := func(s string, bits int) (string, error) {
+		v, err := strconv.ParseUint(s, 10, bits)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a %d-bit number", s, bits)
+		}
+		return strconv.FormatUint(v, 10), nil
+	}
+
+	switch rtype {
+	case "A", "AAAA":
+		if err := need(1); err != nil {
+			return "", err
+		}
+		ip := net.ParseIP(words[0])
+		if ip == nil || (rtype == "A") != (ip.To4() != nil && !strings.Contains(words[0], ":")) {
+			return "", fmt.Errorf("%q is not an %s address", words[0], map[string]string{"A": "IPv4", "AAAA": "IPv6"}[rtype])
+		}
+		return ip.String(), nil
+	case "NS", "CNAME", "PTR", "DNAME":
+		if err := need(1); err != nil {
+			return "", err
+		}
+		return p.absolute(words[0])
+	case "MX":
+		if err := need(2); err != nil {
+			return "", err
+		}
+		pref, err := number(words[0], 16)
+		if err != nil {
+			return "", err
+		}
+		host, err := p.absolute(words[1])
+		return pref + " " + host, err
+	case "SRV":
+		if err := need(4); err != nil {
+			return "", err
+		}
+		values := []string{}
+		for _, w := range words[:3] {
+			v, err := number(w, 16)
+			if err != nil {
+				return "", err
+			}
+			values = append(values, v)
+		}
+		target, err := p.absolute(words[3])
+		return strings.Join(values, " ") + " " + target, err
+	case "SOA":
+		if err := need(7); err != nil {
+			return "", err
+		}
+		mname, err := p.absolute(words[0])
+		if err != nil {
+			return "", err
+		}
+		rname, err := p.absolute(words[1])
+		if err != nil {
+			return "", err
+		}
+		values := []string{mname, rname}
+		for _, w := range words[2:] {
+			v, err := parseTTL(w)
+			if err != nil {
+				return "", err
+			}
+			values = append(values, strconv.FormatUint(uint64(v), 10))
+		}
+		return strings.Join(values, " "), nil
+	case "TXT", "SPF":
+		if len(words) < 1 {
+			return "", fmt.Errorf("no text")
+		}
+		for _, w := range words {
+			if len(w) > 255 {
+				return "", fmt.Errorf("string of %d bytes is longer than 255", len(w))
+			}
+		}
+		return strings.Join(words, ""), nil
+	case "CAA":
+		if err := need(3); err != nil {
+			return "", err
+		}
+		// Flags that are not a number are left for the CAA lint check.
+		flags := words[0]
+		if v, err := number(flags, 8); err == nil {
+			flags = v
+		}
+		return fmt.Sprintf("%s %s %q", flags, words[1], words[2]), nil
+	}
+	return strings.Join(words, " "), nil
+}
@@ -0,0 +1,236 @@
+package pig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeZone writes the given files into a temporary directory and returns
+// the path of the first one.
+func writeZone(t *testing.T, files ...string) string {
+	dir := t.TempDir()
+	first := ""
+	for i := 0; i+1 < len(files); i += 2 {
+		path := filepath.Join(dir, files[i])
+		if err := os.WriteFile(path, []byte(files[i+1]), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if first == "" {
+			first = path
+		}
+	}
+	return first
+}
+
+func TestParseZoneFile(t *testing.T) {
+	tests := []struct {
+		name   string
+		origin string
+		zone   string
+		want   []string
+	}{
+		{
+			name: "origin and relative names",
+			zone: "$ORIGIN Example.COM.\n$TTL 300\nwww A 192.0.2.1\nmail.example.net. A 192.0.2.2\nftp CNAME www\n",
+			want: []string{
+				"www.example.com. 300 A 192.0.2.1",
+				"mail.example.net. 300 A 192.0.2.2",
+				"ftp.example.com. 300 CNAME www.example.com.",
+			},
+		},
+		{
+			name:   "origin argument",
+			origin: "example.org",
+			zone:   "$TTL 300\n@ NS ns1\nns1 A 192.0.2.1\n",
+			want: []string{
+				"example.org. 300 NS ns1.example.org.",
+				"ns1.example.org. 300 A 192.0.2.1",
+			},
+		},
+		{
+			name: "origin changes midway",
+			zone: "$TTL 300\n$ORIGIN a.example.\nwww A 192.0.2.1\n$ORIGIN b.example.\nwww A 192.0.2.2\n@ MX 10 mail\n",
+			want: []string{
+				"www.a.example. 300 A 192.0.2.1",
+				"www.b.example. 300 A 192.0.2.2",
+				"b.example. 300 MX 10 mail.b.example.",
+			},
+		},
+		{
+			name: "ttl units and explicit ttls",
+			zone: "$ORIGIN example.com.\n$TTL 1h30m\na A 192.0.2.1\nb 60 A 192.0.2.2\nc IN 1w A 192.0.2.3\nd 2D IN A 192.0.2.4\n",
+			want: []string{
+				"a.example.com. 5400 A 192.0.2.1",
+				"b.example.com. 60 A 192.0.2.2",
+				"c.example.com. 604800 A 192.0.2.3",
+				"d.example.com. 172800 A 192.0.2.4",
+			},
+		},
+		{
+			name: "ttl from the previous record without $TTL",
+			zone: "$ORIGIN example.com.\na 120 A 192.0.2.1\nb A 192.0.2.2\n",
+			want: []string{
+				"a.example.com. 120 A 192.0.2.1",
+				"b.example.com. 120 A 192.0.2.2",
+			},
+		},
+		{
+			name: "soa minimum as ttl",
+			zone: "$ORIGIN example.com.\n@ SOA ns1 hostmaster 1 7200 3600 1209600 1h\n",
+			want: []string{"example.com. 3600 SOA ns1.example.com. hostmaster.example.com. 1 7200 3600 1209600 3600"},
+		},
+		{
+			name: "parentheses and comments",
+			zone: "$ORIGIN example.com.\n$TTL 300\n@ IN SOA ns1 hostmaster (\n  2024010101 ; serial\n  2h         ; refresh\n  1h 2w      ; retry, expire\n  5m )       ; minimum\n",
+			want: []string{"example.com. 300 SOA ns1.example.com. hostmaster.example.com. 2024010101 7200 3600 1209600 300"},
+		},
+		{
+			name: "quoted strings",
+			zone: "$ORIGIN example.com.\n$TTL 300\n" +
+				"@ TXT \"v=spf1 ip4:192.0.2.0/24\" \" -all\"\n" +
+				"a TXT \"semi;colon (paren)\"\n" +
+				"b TXT \"say \\\"hi\\\"\" \\065\n" +
+				"c TXT \"\\104\\105\\033\"\n" +
+				"@ CAA 0 issue \"letsencrypt.org\"\n",
+			want: []string{
+				"example.com. 300 TXT v=spf1 ip4:192.0.2.0/24 -all",
+				"a.example.com. 300 TXT semi;colon (paren)",
+				"b.example.com. 300 TXT say \"hi\"\\065",
+				"c.example.com. 300 TXT hi!",
+				`example.com. 300 CAA 0 issue "letsencrypt.org"`,
+			},
+		},
+		{
+			name: "at sign and blank owners",
+			zone: "$ORIGIN example.com.\n$TTL 300\n@ NS ns1\n  NS ns2\n\tMX 10 mail\nwww A 192.0.2.1\n    AAAA 2001:DB8::1\n; comment\n\n  TXT \"still www\"\n",
+			want: []string{
+				"example.com. 300 NS ns1.example.com.",
+				"example.com. 300 NS ns2.example.com.",
+				"example.com. 300 MX 10 mail.example.com.",
+				"www.example.com. 300 A 192.0.2.1",
+				"www.example.com. 300 AAAA 2001:db8::1",
+				"www.example.com. 300 TXT still www",
+			},
+		},
+		{
+			name: "numeric fields",
+			zone: "$ORIGIN example.com.\n$TTL 300\n@ MX 010 mail\n_sip._tcp SRV 00 060 05060 sip\n@ CAA 000 issue \"ca.example\"\n@ SOA ns1 hostmaster 01 0 0 0 0300\n",
+			want: []string{
+				"example.com. 300 MX 10 mail.example.com.",
+				"_sip._tcp.example.com. 300 SRV 0 60 5060 sip.example.com.",
+				`example.com. 300 CAA 0 issue "ca.example"`,
+				"example.com. 300 SOA ns1.example.com. hostmaster.example.com. 1 0 0 0 300",
+			},
+		},
+		{
+			name: "other types",
+			zone: "$ORIGIN example.com.\n$TTL 300\n_sip._tcp SRV 10 60 5060 sip\n1.2 PTR host\nx TLSA 3 1 1 abcdef\ny TYPE65280 \\# 0\n",
+			want: []string{
+				"_sip._tcp.example.com. 300 SRV 10 60 5060 sip.example.com.",
+				"1.2.example.com. 300 PTR host.example.com.",
+				"x.example.com. 300 TLSA 3 1 1 abcdef",
+				"y.example.com. 300 TYPE65280 \\# 0",
+			},
+		},
+	}
+	for _, tt := range tests {
+		z, err := ParseZoneFile(writeZone(t, "db", tt.zone), tt.origin)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(z.Errors) > 0 {
+			t.Errorf("%s: errors %v", tt.name, z.Errors)
+		}
+		got := []string{}
+		for _, rec := range z.Records {
+			got = append(got, fmt.Sprintf("%s %d %s %s", rec.Name, rec.TTL, rec.Type, rec.Data))
+		}
+		if !equalStrings(got, tt.want) {
+			t.Errorf("%s:\ngot  %q\nwant %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseZoneFileOriginAndLines(t *testing.T) {
+	path := writeZone(t, "db",
+		"$TTL 300\nexample.net. SOA ns1.example.net. hostmaster.example.net. (\n 1 2 3 4 5 )\nwww.example.net. A 192.0.2.1\n")
+	z, err := ParseZoneFile(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if z.Origin != "example.net." {
+		t.Errorf("origin from the SOA = %q, want example.net.", z.Origin)
+	}
+	if len(z.Records) != 2 || z.Records[0].Line != 2 || z.Records[1].Line != 4 || z.Records[1].File != path {
+		t.Errorf("records %+v, want an SOA at line 2 and an A record at line 4", z.Records)
+	}
+}
+
+func TestParseZoneFileInclude(t *testing.T) {
+	path := writeZone(t,
+		"db", "$ORIGIN example.com.\n$TTL 300\nwww A 192.0.2.1\n$INCLUDE hosts.inc lab.example.com.\nmail A 192.0.2.3\n$INCLUDE loop.inc\n",
+		"hosts.inc", "gw A 192.0.2.2\n",
+		"loop.inc", "$INCLUDE loop.inc\n",
+	)
+	z, err := ParseZoneFile(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"www.example.com.", "gw.lab.example.com.", "mail.example.com."}
+	got := []string{}
+	for _, rec := range z.Records {
+		got = append(got, rec.Name)
+	}
+	if !equalStrings(got, want) {
+		t.Errorf("names %v, want %v", got, want)
+	}
+	if filepath.Base(z.Records[1].File) != "hosts.inc" || z.Records[1].Line != 1 {
+		t.Errorf("included record at %s:%d, want hosts.inc:1", z.Records[1].File, z.Records[1].Line)
+	}
+	if len(z.Errors) != 1 || !strings.Contains(z.Errors[0].Message, "nested more than") {
+		t.Errorf("errors %v, want one for the include loop", z.Errors)
+	}
+}
+
+func TestParseZoneFileErrors(t *testing.T) {
+	tests := []struct {
+		zone string
+		want string
+	}{
+		{"www A 192.0.2.1\n", "relative name www used before $ORIGIN"},
+		{"@ A 192.0.2.1\n", "@ used before $ORIGIN"},
+		{"  A 192.0.2.1\n", "no owner name"},
+		{"$ORIGIN example.com\n", "$ORIGIN needs one absolute name"},
+		{"$ORIGIN example.com.\nwww A 192.0.2.1\n", "no TTL"},
+		{"$ORIGIN example.com.\n$TTL 300\nwww A 2001:db8::1\n", "not an IPv4 address"},
+		{"$ORIGIN example.com.\n$TTL 300\nwww AAAA 192.0.2.1\n", "not an IPv6 address"},
+		{"$ORIGIN example.com.\n$TTL 300\nwww CH A 192.0.2.1\n", "class CH"},
+		{"$ORIGIN example.com.\n$TTL 300\nwww BOGUS 1\n", "unknown record type BOGUS"},
+		{"$ORIGIN example.com.\n$TTL 300\nwww 300\n", "no type"},
+		{"$ORIGIN example.com.\n$TTL 300\n@ MX 70000 mail\n", "16-bit number"},
+		{"$ORIGIN example.com.\n$TTL 1x\n", "invalid TTL"},
+		{"$ORIGIN example.com.\n$TTL 300\n@ SOA ns1 hostmaster ( 1 2 3 4 5\n", "unclosed parenthesis"},
+		{"$ORIGIN example.com.\n$TTL 300\n@ A 192.0.2.1 )\n", "unbalanced closing parenthesis"},
+		{"$ORIGIN example.com.\n$TTL 300\n@ TXT \"open\n", "unterminated quoted string"},
+		{"$ORIGIN example.com.\n$TTL 300\n@ TXT \"\\999\"\n", "invalid escape"},
+		{"$ORIGIN example.com.\n$TTL 300\n@ TXT \"" + strings.Repeat("x", 256) + "\"\n", "longer than 255"},
+		{"$GENERATE 1-10 host$ A 192.0.2.$\n", "$GENERATE is not supported"},
+		{"$INCLUDE missing.inc\n", "$INCLUDE: open"},
+		{"$FOO bar\n", "unknown directive"},
+	}
+	for _, tt := range tests {
+		z, err := ParseZoneFile(writeZone(t, "db", tt.zone), "")
+		if err != nil {
+			t.Fatalf("%q: %v", tt.zone, err)
+		}
+		if len(z.Errors) != 1 || !strings.Contains(z.Errors[0].Message, tt.want) {
+			t.Errorf("%q: errors %v, want one containing %q", tt.zone, z.Errors, tt.want)
+		}
+	}
+	if _, err := ParseZoneFile(filepath.Join(t.TempDir(), "missing"), ""); err == nil {
+		t.Error("ParseZoneFile of a missing file returned no error")
+	}
+}
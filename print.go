@@ -0,0 +1,613 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/donuts-are-good/pig/pkg/pig"
+)
+
+func printCNAMEChain(chain *pig.CNAMEChain) {
+	for _, hop := range chain.Hops {
+		fmt.Printf("%s -> %s (TTL %d)\n", hop.Name, hop.Target, hop.TTL)
+		if hop.Apex {
+			fmt.Printf("-  Warning: CNAME at zone apex %s is not allowed\n", hop.Name)
+		}
+		if len(hop.Conflicts) > 0 {
+			fmt.Printf("-  Warning: %s has a CNAME alongside other data: %s\n", hop.Name, strings.Join(hop.Conflicts, ", "))
+		}
+	}
+	switch {
+	case chain.Self:
+		fmt.Println("-  Warning: CNAME points to itself")
+	case chain.Loop:
+		fmt.Println("-  Warning: CNAME loop detected")
+	case chain.TooLong:
+		fmt.Printf("-  Warning: CNAME chain longer than %d hops\n", pig.MaxCNAMEChain)
+	}
+}
+
+func printTakeover(scanner *pig.Scanner, res *pig.TakeoverResult) {
+	provider := res.Provider
+	if provider == "" {
+		provider = scanner.DetectService(res.Target)
+	}
+	fmt.Printf("%s -> %s (%s)\n", res.Domain, res.Target, provider)
+	if res.NXDOMAIN {
+		fmt.Println("-  CNAME target does not resolve (NXDOMAIN)")
+	}
+	if res.Fingerprint != "" {
+		fmt.Printf("-  Unclaimed resource fingerprint: %q\n", res.Fingerprint)
+	}
+	fmt.Printf("-  Takeover risk: %s\n", res.Risk)
+}
+
+func printVendors(vendors []pig.Vendor) {
+	if len(vendors) < 1 {
+		return
+	}
+	fmt.Println("\n[Inferred Vendors]")
+	for _, v := range vendors {
+		fmt.Printf("%s (%s): %.0f%% confidence\n", v.Service, v.Category, v.Confidence*100)
+		for _, ev := range v.Evidence {
+			fmt.Printf("-  %s: %s\n", ev.Source, ev.Value)
+		}
+	}
+}
+
+func printGeo(geo *pig.Geolocation) {
+	if geo == nil {
+		return
+	}
+
+	fmt.Printf("-  Country: %s, Region: %s, City: %s\n", geo.Country, geo.Region, geo.City)
+	extra := []string{}
+	if geo.Postal != "" {
+		extra = append(extra, "Postal: "+geo.Postal)
+	}
+	if geo.Latitude != 0 || geo.Longitude != 0 {
+		extra = append(extra, fmt.Sprintf("Coordinates: %.4f,%.4f", geo.Latitude, geo.Longitude))
+	}
+	if geo.TimeZone != "" {
+		extra = append(extra, "Time Zone: "+geo.TimeZone)
+	}
+	if geo.Org != "" {
+		extra = append(extra, "Org: "+geo.Org)
+	}
+	if len(extra) > 0 {
+		fmt.Println("-  " + strings.Join(extra, ", "))
+	}
+}
+
+func printASN(info *pig.ASNInfo) {
+	fmt.Printf("ASN: %s, Name: %s, AllocatedAt: %s, Country: %s, Range: %s, Registry: %s\n",
+		info.ASN, info.Name, info.Allocated, info.Country, info.Prefix, info.Registry)
+	if len(info.Origins) > 1 {
+		fmt.Printf("-  Announced by multiple origins: AS%s\n", strings.Join(info.Origins, ", AS"))
+	}
+	if len(info.Peers) > 0 {
+		fmt.Printf("-  Peers: AS%s\n", strings.Join(info.Peers, ", AS"))
+	}
+}
+
+func printNetworks(groups []*pig.NetworkGroup) {
+	if len(groups) < 1 {
+		return
+	}
+	roles := []string{}
+	dnsASNs, dnsAddrs := 0, 0
+	for _, role := range pig.NetworkRoles {
+		for _, group := range groups {
+			if len(group.Roles[role]) > 0 {
+				roles = append(roles, role)
+				break
+			}
+		}
+	}
+	for _, group := range groups {
+		if len(group.Roles["dns"]) > 0 {
+			dnsASNs++
+			dnsAddrs += len(group.Roles["dns"])
+		}
+	}
+
+	fmt.Println("\n[ASN Concentration]")
+	for _, group := range groups {
+		parts := []string{}
+		for _, role := range roles {
+			if ips := group.Roles[role]; len(ips) > 0 {
+				parts = append(parts, fmt.Sprintf("%s (%s)", role, strings.Join(ips, ", ")))
+			}
+		}
+		fmt.Printf("AS%s %s: %s\n", group.ASN, group.Name, strings.Join(parts, "; "))
+	}
+
+	if len(groups) == 1 && len(roles) > 1 {
+		fmt.Printf("-  Warning: %s all sit on AS%s, a single point of failure\n", strings.Join(roles, ", "), groups[0].ASN)
+	}
+	if dnsASNs == 1 && dnsAddrs > 1 {
+		fmt.Println("-  Warning: all nameservers are on a single network")
+	}
+}
+
+func printDNSBLResult(res *pig.BlocklistResult) {
+	switch {
+	case res.Error != "":
+		fmt.Printf("-  %s %s for %s\n", res.List, res.Error, res.Target)
+	case res.Refused:
+		fmt.Printf("-  %s refused the query for %s: %s\n", res.List, res.Target, strings.Join(res.Reasons, ", "))
+	case res.Score != nil:
+		fmt.Printf("-  %s for %s: %d\n", res.List, res.Target, *res.Score)
+	case res.Listed:
+		fmt.Printf("-  %s is listed on blacklist: %s (%s): %s\n", res.Target, res.List, res.Zone, strings.Join(res.Reasons, ", "))
+		for _, txt := range res.TXT {
+			fmt.Printf("   %s\n", txt)
+		}
+	}
+}
+
+func printDomainBlocklists(report *pig.DomainBlocklistReport) {
+	if report == nil || len(report.Lists) < 1 {
+		return
+	}
+	fmt.Println("\n[Domain Blocklists]")
+	for _, list := range report.Lists {
+		clean := true
+		for _, res := range report.Results {
+			if res.List == list {
+				clean = false
+				printDNSBLResult(res)
+			}
+		}
+		if clean {
+			fmt.Printf("%s: not listed\n", list)
+		}
+	}
+}
+
+func printFCrDNS(res pig.FCrDNSResult) {
+	status := "FCrDNS fail"
+	if res.Pass() {
+		status = "FCrDNS pass"
+	}
+	if len(res.PTRs) < 1 {
+		fmt.Printf("%s: no PTR record (%s)\n", res.IP, status)
+		return
+	}
+	fmt.Printf("%s -> %s (%s)\n", res.IP, strings.Join(res.PTRs, ", "), status)
+}
+
+func printPassiveDNS(domain string, ips []net.IP, report *pig.PassiveDNSReport) {
+	if report == nil {
+		return
+	}
+	fmt.Println("\n[Passive DNS]")
+	fmt.Printf("Historical A/AAAA records for %s:\n", strings.ToLower(strings.TrimSuffix(domain, ".")))
+	if len(report.History) < 1 {
+		fmt.Println("-  none")
+	}
+	for _, rec := range report.History {
+		fmt.Printf("-  %s %s (%s)\n", rec.RRType, rec.RData, rec.Seen())
+	}
+
+	for _, ip := range ips {
+		neighbors := report.Neighbors[ip.String()]
+		fmt.Printf("Other names seen on %s:\n", ip)
+		if len(neighbors) < 1 {
+			fmt.Println("-  none")
+		}
+		for _, rec := range neighbors {
+			fmt.Printf("-  %s (%s)\n", rec.RRName, rec.Seen())
+		}
+	}
+}
+
+func printAddress(info *pig.AddressInfo) {
+	if info.GeoError != "" {
+		fmt.Println("-  Error fetching geolocation:", info.GeoError)
+	}
+	printGeo(info.Geo)
+	if info.ASNError != "" {
+		fmt.Println("-  Error in ASN lookup:", info.ASNError)
+	}
+	if info.ASN != nil {
+		printASN(info.ASN)
+	}
+	for _, res := range info.Blocklists {
+		printDNSBLResult(res)
+	}
+}
+
+func printReport(scanner *pig.Scanner, r *pig.Report) {
+	if r.Has("addresses") || r.Has("blocklists") {
+		if len(r.Addresses) > 0 {
+			fmt.Println("\n[A & AAAA Records]")
+		}
+		for _, addr := range r.Addresses {
+			if addr.IP.To4() != nil {
+				fmt.Println(addr.IP.String())
+				printAddress(addr)
+			}
+		}
+		for _, addr := range r.Addresses {
+			if addr.IP.To4() == nil {
+				fmt.Println("AAAA: " + addr.IP.String())
+				printAddress(addr)
+			}
+		}
+	}
+
+	if r.CNAME != nil {
+		fmt.Println("\n[CNAME Chain]")
+		printCNAMEChain(r.CNAME)
+		analyzeCNAME(scanner, r.CNAME.Target())
+	}
+	if r.Takeover != nil {
+		fmt.Println("\n[Subdomain Takeover]")
+		printTakeover(scanner, r.Takeover)
+	}
+
+	if len(r.MX) > 0 && r.Has("mx") {
+		fmt.Println("\n[MX Records]")
+		for _, mx := range r.MX {
+			fmt.Printf("%s: %s %v\n", mx.Service, mx.Host, mx.Pref)
+			for _, res := range mx.Blocklists {
+				printDNSBLResult(res)
+			}
+		}
+		analyzeMX(r.MX)
+	}
+	if len(r.NS) > 0 && r.Has("ns") {
+		fmt.Println("\n[NS Records]")
+		for _, ns := range r.NS {
+			fmt.Printf("%s: %s\n", ns.Service, ns.Host)
+		}
+		analyzeNS(r.NS)
+	}
+	printTransports(r.Transports, r.Profile)
+	printDNSSEC(r.DNSSEC)
+
+	printReverseDNS(r)
+	printPassiveDNS(r.Domain, r.IPs(), r.Passive)
+
+	if len(r.TXT) > 0 && r.Has("spf") {
+		fmt.Println("\n[SPF Records]")
+		for _, spf := range r.SPF {
+			fmt.Println(spf)
+		}
+		analyzeSPF(r.SPF)
+	}
+	if r.Has("spf") {
+		fmt.Println("\n[DMARC]")
+		if len(r.DMARC) < 1 {
+			fmt.Printf("No DMARC record at _dmarc.%s\n", r.Domain)
+		}
+		for _, dmarc := range r.DMARC {
+			fmt.Println(dmarc)
+		}
+	}
+	if len(r.SRV) > 0 && r.Has("srv") {
+		fmt.Println("\n[SRV Records]")
+		for _, srv := range r.SRV {
+			fmt.Printf("%s:%d %d %d\n", srv.Target, srv.Port, srv.Priority, srv.Weight)
+		}
+		analyzeSRV(r.SRV)
+	}
+	if len(r.TXT) > 0 && r.Has("txt") {
+		fmt.Println("\n[TXT Records]")
+		for _, txt := range r.TXT {
+			fmt.Println(txt)
+		}
+		analyzeTXT(r.TXT)
+	}
+
+	printDomainBlocklists(r.DomainBlocklists)
+	printNetworks(r.Networks)
+	printVendors(r.Vendors)
+
+	if r.Has("zonetransfer") {
+		printZoneTransfer(r.ZoneTransfer)
+	}
+	if r.Has("amplification") {
+		printAmplification(r.Amplification)
+	}
+	if r.Has("axfr") {
+		printAXFR(r.AXFR)
+	}
+	printScore(r.Score)
+	printLookups(r.Lookups)
+	printProbes(r)
+}
+
+func printScore(card *pig.Scorecard) {
+	if card == nil {
+		return
+	}
+	fmt.Println("\n[Grades]")
+	for _, grade := range card.Grades {
+		findings := "findings"
+		if grade.Findings == 1 {
+			findings = "finding"
+		}
+		fmt.Printf("%s: %s (%d/100, %d %s)\n", strings.ToUpper(grade.Category), grade.Grade, grade.Score, grade.Findings, findings)
+	}
+	if len(card.Findings) < 1 {
+		return
+	}
+	fmt.Println("\n[Findings]")
+	for _, f := range card.Findings {
+		fmt.Printf("%s [%s] %s: %s\n", strings.ToUpper(string(f.Severity)), f.ID, strings.ToUpper(f.Category), f.Title)
+		fmt.Printf("-  %s\n", f.Description)
+		for _, ev := range f.Evidence {
+			fmt.Printf("-  Evidence: %s\n", ev)
+		}
+		if f.Remediation != "" {
+			fmt.Printf("-  Fix: %s\n", f.Remediation)
+		}
+	}
+}
+
+// printLookups lists the lookups that got no usable answer, or every
+// lookup with -verbose.
+func printLookups(lookups []pig.Lookup) {
+	shown := []pig.Lookup{}
+	for _, l := range lookups {
+		if verbose || l.Failed() {
+			shown = append(shown, l)
+		}
+	}
+	if len(shown) < 1 {
+		return
+	}
+	if verbose {
+		fmt.Println("\n[Queries]")
+	} else {
+		fmt.Println("\n[Lookup Errors]")
+	}
+	for _, l := range shown {
+		server := ""
+		if l.Server != "" {
+			server = " @" + l.Server
+		}
+		fmt.Printf("%s %s%s: %s (%s)\n", l.Type, l.Name, server, describeLookup(l), l.Duration.Round(100*time.Microsecond))
+	}
+}
+
+func describeLookup(l pig.Lookup) string {
+	switch l.Outcome {
+	case pig.OutcomeData:
+		if l.Answers == 1 {
+			return "NOERROR, 1 record"
+		}
+		return fmt.Sprintf("NOERROR, %d records", l.Answers)
+	case pig.OutcomeNoData:
+		return "NOERROR, no records"
+	case pig.OutcomeTimeout:
+		return "timed out"
+	case pig.OutcomeNetwork:
+		return "network error: " + l.Error
+	}
+	return l.Rcode
+}
+
+func printProbes(r *pig.Report) {
+	fmt.Println("\n[Probes Sent]")
+	fmt.Println("Profile:", r.Profile)
+	if len(r.Probes) < 1 {
+		fmt.Println("No probes sent, only queries to the recursive resolver")
+		return
+	}
+	for _, probe := range r.Probes {
+		fmt.Printf("-  %s %s (%d)\n", probe.Kind, probe.Target, probe.Count)
+	}
+}
+
+func printReverseDNS(r *pig.Report) {
+	mxCount := 0
+	for _, mx := range r.MX {
+		mxCount += len(mx.Reverse)
+	}
+	if len(r.Addresses) < 1 && mxCount < 1 {
+		return
+	}
+	fmt.Println("\n[Reverse DNS]")
+	for _, addr := range r.Addresses {
+		if addr.Reverse != nil {
+			printFCrDNS(*addr.Reverse)
+		}
+	}
+	for _, mx := range r.MX {
+		if len(mx.Reverse) < 1 {
+			continue
+		}
+		fmt.Printf("MX %s\n", mx.Host)
+		for _, res := range mx.Reverse {
+			printFCrDNS(res)
+		}
+	}
+}
+
+func printTransports(t *pig.TransportReport, profile pig.Profile) {
+	if t == nil {
+		return
+	}
+	fmt.Println("\n[Encrypted Transports]")
+	if len(t.HTTPS) < 1 {
+		fmt.Println("No HTTPS records")
+	}
+	if len(t.SVCB) < 1 {
+		fmt.Println("No SVCB records at _dns names")
+	}
+	for _, records := range [][]pig.SVCBRecord{t.HTTPS, t.SVCB} {
+		for _, r := range records {
+			fmt.Printf("%s %s: %s\n", r.Type, strings.TrimSuffix(r.Name, "."), r.String())
+			if r.Alias() {
+				fmt.Printf("-  Alias of %s\n", strings.TrimSuffix(r.Target, "."))
+				continue
+			}
+			if len(r.ALPN) > 0 {
+				fmt.Printf("-  ALPN: %s\n", strings.Join(r.ALPN, ", "))
+			}
+			if r.ECH != "" {
+				fmt.Println("-  Encrypted Client Hello configured")
+			}
+			if r.DoHPath != "" {
+				fmt.Printf("-  DNS over HTTPS at %s\n", r.DoHPath)
+			}
+		}
+	}
+	if profile != pig.Active {
+		fmt.Println("DNS over TLS not probed, pass -active to check port 853 of the nameservers")
+		return
+	}
+	for _, check := range t.DoT {
+		fmt.Printf("DoT %s (%s): %s\n", strings.TrimSuffix(check.Server, "."), check.Address, describeDoT(check))
+	}
+}
+
+func describeDoT(c pig.DoTCheck) string {
+	switch {
+	case !c.Connect:
+		return "port 853 closed (" + c.Error + ")"
+	case !c.Handshake:
+		return "TLS handshake failed (" + c.Error + ")"
+	}
+	parts := []string{c.TLSVersion}
+	if c.Verified {
+		parts = append(parts, "certificate valid")
+	} else {
+		parts = append(parts, "certificate not valid ("+c.CertError+")")
+	}
+	switch {
+	case !c.Answered:
+		parts = append(parts, "no DNS answer ("+c.Error+")")
+	case c.Authoritative:
+		parts = append(parts, "answers authoritatively")
+	default:
+		parts = append(parts, "answers without the AA flag")
+	}
+	return strings.Join(parts, ", ")
+}
+
+func printDNSSEC(status *pig.DNSSECStatus) {
+	if status == nil {
+		return
+	}
+	fmt.Println("\n[DNSSEC]")
+	if status.Detail != "" {
+		fmt.Printf("%s (%s)\n", status.Status, status.Detail)
+	} else {
+		fmt.Println(status.Status)
+	}
+	if status.Status == "bogus" {
+		fmt.Println("-  Warning: DNSSEC validation fails, validating resolvers will not resolve this domain")
+	}
+}
+
+func printRateLimit(check pig.RateLimitCheck) {
+	fmt.Println("  Checking for rate limiting:")
+	if check.Attempt > 0 {
+		fmt.Printf("    Attempt %d took %s. Possible rate limiting detected.\n", check.Attempt, check.Elapsed)
+	} else {
+		fmt.Println("    No obvious rate limiting detected.")
+	}
+}
+
+func printZoneTransfer(checks []pig.ZoneTransferCheck) {
+	fmt.Println("\n[Zone Transfer Vulnerability Check]")
+	for _, check := range checks {
+		fmt.Printf("Checking %s:\n", check.Server)
+		if check.AXFR {
+			fmt.Println("  WARNING: AXFR (full zone transfer) is allowed!")
+			fmt.Printf("  Received %d records in AXFR response\n", check.AXFRRecords)
+		} else {
+			fmt.Println("  AXFR not allowed")
+		}
+		if check.IXFR {
+			fmt.Println("  WARNING: IXFR (incremental zone transfer) is allowed!")
+			fmt.Printf("  Received %d records in IXFR response\n", check.IXFRRecords)
+		} else {
+			fmt.Println("  IXFR not allowed")
+		}
+		if check.TCP {
+			fmt.Println("  TCP port 53 is open (required for zone transfers)")
+		} else {
+			fmt.Println("  TCP port 53 is closed or filtered")
+		}
+		if check.DNSSEC {
+			fmt.Println("  DNSSEC is enabled, which may provide additional security")
+		} else {
+			fmt.Println("  DNSSEC does not appear to be enabled")
+		}
+		printRateLimit(check.RateLimit)
+	}
+}
+
+func printAmplification(checks []pig.AmplificationCheck) {
+	fmt.Println("\n[DNS Amplification Vulnerability Check]")
+	for _, check := range checks {
+		fmt.Printf("%s query:\n", check.Type)
+		fmt.Printf("  Query size: %d bytes\n", check.QuerySize)
+		fmt.Printf("  Response size: %d bytes\n", check.ResponseSize)
+		fmt.Printf("  Amplification factor: %.2f\n", check.Factor)
+		if check.Factor > 4 {
+			fmt.Printf("  Warning: High amplification factor for %s query\n", check.Type)
+		}
+	}
+}
+
+func printAXFR(checks []pig.AXFRCheck) {
+	fmt.Println("\n[AXFR/IFXR Check]")
+	fmt.Println("\n[AXFR Check]")
+	for _, check := range checks {
+		fmt.Printf("Attempting AXFR from %s:\n", check.Server)
+		if check.Error != "" {
+			fmt.Printf("  Error executing dig command: %s\n", check.Error)
+			continue
+		}
+
+		switch {
+		case check.Refused:
+			fmt.Println("  AXFR not allowed")
+		case check.Allowed:
+			fmt.Println("  AXFR allowed! Analyzing transfer:")
+			fmt.Printf("  Total records transferred: %d\n", check.Records)
+			fmt.Println("  Record type distribution:")
+			for rtype, count := range check.Types {
+				fmt.Printf("    %s: %d\n", rtype, count)
+			}
+
+			for _, info := range []string{"AAAA", "MX", "TXT", "SRV"} {
+				if count, ok := check.Types[info]; ok {
+					fmt.Printf("  Warning: %d %s records found. These may contain sensitive information.\n", count, info)
+				}
+			}
+			if check.Types["SOA"] != 2 {
+				fmt.Println("  Warning: Unusual number of SOA records. Expected 2 (start and end of transfer).")
+			}
+			if check.Types["NS"] < 2 {
+				fmt.Println("  Warning: Less than 2 NS records found. This is unusual for a valid zone.")
+			}
+
+			fmt.Println("  Attempting IXFR to check for incremental transfer support:")
+			if check.IXFR {
+				fmt.Println("    IXFR might be supported. This could be a security risk if unintended.")
+			} else {
+				fmt.Println("    IXFR not supported or not allowed")
+			}
+		default:
+			fmt.Println("  No AXFR data received. Transfer might be restricted or server might not support AXFR.")
+		}
+		printRateLimit(check.RateLimit)
+	}
+}
+
+// plural picks the form of a word for a count.
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
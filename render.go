@@ -0,0 +1,122 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/donuts-are-good/pig/pkg/pig"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// templateFiles maps the template formats to the name of their embedded
+// template, which is also the name of the override file in ~/.config/pig.
+var templateFiles = map[string]string{
+	"html":     "report.html.tmpl",
+	"markdown": "report.md.tmpl",
+}
+
+var templateFuncs = map[string]interface{}{
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"host": func(name string) string {
+		return strings.TrimSuffix(name, ".")
+	},
+	"ips": func(ips []net.IP) []string {
+		out := []string{}
+		for _, ip := range ips {
+			out = append(out, ip.String())
+		}
+		return out
+	},
+	"failed": func(lookups []pig.Lookup) []pig.Lookup {
+		failed := []pig.Lookup{}
+		for _, l := range lookups {
+			if l.Failed() {
+				failed = append(failed, l)
+			}
+		}
+		return failed
+	},
+	"describe":  describeLookup,
+	"dotstatus": describeDoT,
+	"round": func(d time.Duration) time.Duration {
+		return d.Round(100 * time.Microsecond)
+	},
+	"listed": func(results []*pig.BlocklistResult) []string {
+		listed := []string{}
+		for _, res := range results {
+			switch {
+			case res.Error != "":
+				listed = append(listed, fmt.Sprintf("%s %s", res.List, res.Error))
+			case res.Refused:
+				listed = append(listed, fmt.Sprintf("%s refused the query", res.List))
+			case res.Score != nil:
+				listed = append(listed, fmt.Sprintf("%s score %d", res.List, *res.Score))
+			case res.Listed:
+				listed = append(listed, fmt.Sprintf("%s on %s (%s)", res.Target, res.List, strings.Join(res.Reasons, ", ")))
+			}
+		}
+		return listed
+	},
+	"fcrdns": func(res pig.FCrDNSResult) string {
+		if res.Pass() {
+			return "pass"
+		}
+		return "fail"
+	},
+	"mechanisms": func(spf string) []string {
+		return strings.Fields(spf)[1:]
+	},
+	"percent": func(f float64) string {
+		return fmt.Sprintf("%.0f%%", f*100)
+	},
+	// md escapes a value for a Markdown table cell.
+	"md": func(s string) string {
+		s = strings.ReplaceAll(s, "|", "\\|")
+		return strings.Join(strings.Fields(s), " ")
+	},
+}
+
+// renderReport writes the report through the template for the format: the
+// last of ~/.config/pig/<name> and -template when either is given, or the
+// embedded one.
+func renderReport(out io.Writer, format string, report *pig.Report) error {
+	name := templateFiles[format]
+	var overrides []string
+	if templateFile != "" {
+		overrides = []string{templateFile}
+	}
+	var text []byte
+	var err error
+	if paths := configPaths(name, overrides); len(paths) > 0 {
+		text, err = os.ReadFile(paths[len(paths)-1])
+	} else {
+		text, err = templateFS.ReadFile("templates/" + name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if format == "html" {
+		tmpl, err := htmltemplate.New(name).Funcs(templateFuncs).Parse(string(text))
+		if err != nil {
+			return err
+		}
+		return tmpl.Execute(out, report)
+	}
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(string(text))
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(out, report)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/donuts-are-good/pig/pkg/pig"
+)
+
+// renderTestReport fills every section the templates print.
+func renderTestReport() *pig.Report {
+	score := 20
+	return &pig.Report{
+		Domain:  "example.com",
+		Time:    time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Profile: pig.Active,
+		Probes:  []pig.Probe{{Kind: "axfr", Target: "ns1.example.com.", Count: 1}},
+		Lookups: []pig.Lookup{
+			{Name: "example.com.", Type: "A", Outcome: pig.OutcomeData, Answers: 1},
+			{Name: "example.com.", Type: "CAA", Outcome: pig.OutcomeServFail, Rcode: "SERVFAIL", Duration: 1500 * time.Microsecond},
+		},
+		Records: []pig.Record{
+			{Name: "example.com.", Type: "A", TTL: 300, Data: "192.0.2.1"},
+			{Name: "example.com.", Type: "TXT", TTL: 300, Data: "<script>alert(1)</script> a|b"},
+		},
+		Addresses: []*pig.AddressInfo{{
+			IP:         net.ParseIP("192.0.2.1"),
+			Geo:        &pig.Geolocation{City: "Oslo", Region: "Oslo", Country: "NO"},
+			ASN:        &pig.ASNInfo{ASN: "64500", Name: "EXAMPLE-NET", Prefix: "192.0.2.0/24"},
+			Blocklists: []*pig.BlocklistResult{{List: "Example RBL", Zone: "rbl.example", Target: "192.0.2.1", Listed: true, Reasons: []string{"spam"}}},
+			Reverse:    &pig.FCrDNSResult{IP: net.ParseIP("192.0.2.1"), PTRs: []string{"host.example.com."}, Confirmed: []string{"host.example.com."}},
+		}},
+		CNAME:    &pig.CNAMEChain{Name: "example.com.", Hops: []pig.CNAMEHop{{Name: "www.example.com.", Target: "cdn.example.net.", TTL: 60, Conflicts: []string{"TXT"}}}},
+		Takeover: &pig.TakeoverResult{Domain: "www.example.com.", Target: "cdn.example.net.", Provider: "Example CDN", NXDOMAIN: true, Risk: "high"},
+		MX: []pig.MXInfo{{
+			Host:       "mail.example.com.",
+			Pref:       10,
+			Service:    "Example Mail",
+			Addresses:  []net.IP{net.ParseIP("192.0.2.25")},
+			Reverse:    []pig.FCrDNSResult{{IP: net.ParseIP("192.0.2.25")}},
+			Blocklists: []*pig.BlocklistResult{{List: "Mail RBL", Zone: "mail.rbl.example", Target: "192.0.2.25", Listed: true, Reasons: []string{"open relay"}}},
+		}},
+		NS: []pig.NSInfo{{Host: "ns1.example.com.", Service: "Example DNS", Addresses: []net.IP{net.ParseIP("192.0.2.53")}}},
+		Transports: &pig.TransportReport{
+			HTTPS: []pig.SVCBRecord{{Name: "example.com.", Type: "HTTPS", Priority: 1, Target: ".", ALPN: []string{"h2", "h3"}}},
+			DoT: []pig.DoTCheck{
+				{Server: "ns1.example.com.", Address: "192.0.2.53", Connect: true, Handshake: true, TLSVersion: "TLS 1.3", Verified: true, Answered: true, Authoritative: true},
+				{Server: "ns2.example.com.", Address: "192.0.2.54", Error: "connection refused"},
+			},
+		},
+		DNSSEC:           &pig.DNSSECStatus{Status: "bogus", Detail: "expired signature"},
+		SPF:              []string{"v=spf1 include:_spf.example.net -all"},
+		DMARC:            []string{"v=DMARC1; p=reject"},
+		SRV:              []pig.SRVInfo{{Target: "sip.example.com.", Port: 5060, Priority: 10, Weight: 60}},
+		TXT:              []string{"<script>alert(1)</script>"},
+		DomainBlocklists: &pig.DomainBlocklistReport{Lists: []string{"dbl.example"}, Targets: []string{"example.com"}, Results: []*pig.BlocklistResult{{List: "DBL", Target: "example.com", Score: &score}}},
+		Networks:         []*pig.NetworkGroup{{ASN: "64500", Name: "EXAMPLE-NET", Roles: map[string][]string{"web": {"192.0.2.1"}}}},
+		Vendors:          []pig.Vendor{{Service: "Example Mail", Category: "email", Confidence: 0.9}},
+		ZoneTransfer:     []pig.ZoneTransferCheck{{Server: "ns1.example.com.", AXFR: true, AXFRRecords: 12}},
+		Amplification:    []pig.AmplificationCheck{{Type: "ANY", QuerySize: 40, ResponseSize: 2000, Factor: 50}},
+		AXFR:             []pig.AXFRCheck{{Server: "ns2.example.com.", Refused: true}},
+		Score: &pig.Scorecard{
+			Grades:   []pig.Grade{{Category: "dns", Grade: "C", Score: 75, Findings: 1}},
+			Findings: []pig.Finding{{ID: "axfr-open", Category: "dns", Severity: pig.SeverityHigh, Title: "Zone transfer allowed", Description: "Anyone can list the zone.", Remediation: "Restrict AXFR.", Evidence: []string{"ns1.example.com."}}},
+		},
+	}
+}
+
+func renderTest(t *testing.T, format string, report *pig.Report) string {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	var out bytes.Buffer
+	if err := renderReport(&out, format, report); err != nil {
+		t.Fatalf("rendering %s: %v", format, err)
+	}
+	return out.String()
+}
+
+func TestRenderReport(t *testing.T) {
+	for _, format := range []string{"html", "markdown"} {
+		out := renderTest(t, format, renderTestReport())
+		for _, want := range []string{
+			"example.com",
+			"Zone transfer allowed",
+			"Restrict AXFR.",
+			"192.0.2.25 on Mail RBL (open relay)",
+			"192.0.2.1 on Example RBL (spam)",
+			"TLS 1.3, certificate valid, answers authoritatively",
+			"port 853 closed (connection refused)",
+			"DBL score 20",
+			"expired signature",
+			"sip.example.com",
+			"SERVFAIL",
+			"90%",
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("%s output is missing %q", format, want)
+			}
+		}
+	}
+}
+
+func TestRenderReportWithoutScore(t *testing.T) {
+	grades := map[string]string{"html": `<div class="grades">`, "markdown": "## Grades"}
+	for format, marker := range grades {
+		if out := renderTest(t, format, renderTestReport()); !strings.Contains(out, marker) {
+			t.Errorf("%s output has no grades", format)
+		}
+		report := renderTestReport()
+		report.Score = nil
+		report.Sections = []string{"records"}
+		if out := renderTest(t, format, report); strings.Contains(out, marker) {
+			t.Errorf("%s output has grades without a score", format)
+		}
+	}
+}
+
+func TestRenderHTMLEscapes(t *testing.T) {
+	html := renderTest(t, "html", renderTestReport())
+	if strings.Contains(html, "<script>") {
+		t.Error("HTML output contains an unescaped <script> tag from a TXT record")
+	}
+	if !strings.Contains(html, "&lt;script&gt;alert(1)&lt;/script&gt;") {
+		t.Error("HTML output is missing the escaped TXT record")
+	}
+
+	md := renderTest(t, "markdown", renderTestReport())
+	if !strings.Contains(md, `a\|b`) {
+		t.Error("Markdown output does not escape | in a table cell")
+	}
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/donuts-are-good/pig/pkg/pig"
+)
+
+var errBusy = errors.New("too many scans in progress")
+
+type cacheEntry struct {
+	done    chan struct{}
+	value   interface{}
+	err     error
+	expires time.Time
+}
+
+// resultCache shares results across requests. Concurrent requests for the
+// same key wait on a single computation instead of starting their own.
+type resultCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	max     int
+	entries map[string]*cacheEntry
+	hits    uint64
+	misses  uint64
+}
+
+func newResultCache(ttl time.Duration, max int) *resultCache {
+	return &resultCache{ttl: ttl, max: max, entries: map[string]*cacheEntry{}}
+}
+
+func (c *resultCache) get(key string, compute func() (interface{}, error)) *cacheEntry {
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok && (e.expires.IsZero() || time.Now().Before(e.expires)) {
+		c.hits++
+		c.mu.Unlock()
+		return e
+	}
+	c.misses++
+	e := &cacheEntry{done: make(chan struct{})}
+	c.entries[key] = e
+	c.evict()
+	c.mu.Unlock()
+
+	go func() {
+		value, err := compute()
+		c.mu.Lock()
+		e.value, e.err = value, err
+		e.expires = time.Now().Add(c.ttl)
+		if err != nil {
+			// Failures are not worth keeping, the next request retries.
+			delete(c.entries, key)
+		}
+		c.evict()
+		c.mu.Unlock()
+		close(e.done)
+	}()
+	return e
+}
+
+// evict drops expired entries, then the ones closest to expiry until the
+// cache fits. Entries still being computed are kept. Callers hold c.mu.
+func (c *resultCache) evict() {
+	now := time.Now()
+	finished := []string{}
+	for key, e := range c.entries {
+		if e.expires.IsZero() {
+			continue
+		}
+		if now.After(e.expires) {
+			delete(c.entries, key)
+			continue
+		}
+		finished = append(finished, key)
+	}
+	if len(c.entries) <= c.max {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		return c.entries[finished[i]].expires.Before(c.entries[finished[j]].expires)
+	})
+	for _, key := range finished {
+		if len(c.entries) <= c.max {
+			break
+		}
+		delete(c.entries, key)
+	}
+}
+
+func (c *resultCache) stats() (hits, misses uint64, size int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses, len(c.entries)
+}
+
+type routeMetrics struct {
+	codes    map[int]uint64
+	duration time.Duration
+	count    uint64
+}
+
+type serverMetrics struct {
+	mu       sync.Mutex
+	routes   map[string]*routeMetrics
+	scans    uint64
+	inFlight int
+}
+
+func (m *serverMetrics) observe(route string, code int, elapsed time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, ok := m.routes[route]
+	if !ok {
+		r = &routeMetrics{codes: map[int]uint64{}}
+		m.routes[route] = r
+	}
+	r.codes[code]++
+	r.duration += elapsed
+	r.count++
+}
+
+type apiServer struct {
+	scanner *pig.Scanner
+	cache   *resultCache
+	metrics *serverMetrics
+	scans   chan struct{}
+	timeout time.Duration
+	// queue is how long a scan waits for a free slot before failing with
+	// errBusy.
+	queue time.Duration
+}
+
+func serveCommand(args []string) {
+	fs := commandFlags("serve", "")
+	listen := fs.String("listen", ":8080", "address to listen on")
+	timeout := fs.Duration("timeout", 60*time.Second, "how long a request waits for its result")
+	cacheTTL := fs.Duration("cache-ttl", 5*time.Minute, "how long results are cached")
+	cacheSize := fs.Int("cache-size", 1000, "maximum number of cached results")
+	concurrency := fs.Int("concurrency", 4, "maximum number of scans running at once")
+	if len(parseCommand(fs, args)) > 0 || *concurrency < 1 || *cacheSize < 1 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	// Anyone who can reach the API picks the domain, so the active probes
+	// would be sent wherever they ask.
+	if activeConsent || pig.Profile(profileName) == pig.Active {
+		fmt.Fprintln(os.Stderr, "serve does not run the active profile: zone transfer, TCP, DNS over TLS and amplification probes would be sent to any domain a client asks for.")
+		os.Exit(exitUsage)
+	}
+
+	s := &apiServer{
+		scanner: newScanner(),
+		cache:   newResultCache(*cacheTTL, *cacheSize),
+		metrics: &serverMetrics{routes: map[string]*routeMetrics{}},
+		scans:   make(chan struct{}, *concurrency),
+		timeout: *timeout,
+		queue:   *timeout,
+	}
+	srv := &http.Server{
+		Addr:              *listen,
+		Handler:           s.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		WriteTimeout:      *timeout + 10*time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+
+	fmt.Println("Listening on", *listen)
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		fmt.Fprintln(os.Stderr, "Error starting server:", err)
+		os.Exit(exitFailure)
+	}
+}
+
+func (s *apiServer) handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		route := s.route(rec, r)
+		s.metrics.observe(route, rec.status, time.Since(start))
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+var domainEndpoints = map[string]bool{"report": true, "records": true, "spf": true, "dnssec": true}
+
+// route dispatches the request and returns the route template used as the
+// metrics label.
+func (s *apiServer) route(w http.ResponseWriter, r *http.Request) string {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeError(w, http.StatusMethodNotAllowed, "only GET is supported")
+		return "other"
+	}
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.URL.Path == "/metrics":
+		s.writeMetrics(w)
+		return "/metrics"
+	case r.URL.Path == "/healthz":
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+		return "/healthz"
+	case len(parts) == 4 && parts[0] == "v1" && parts[1] == "domains":
+		domain := strings.TrimSuffix(strings.ToLower(parts[2]), ".")
+		route := "/v1/domains/{domain}/" + parts[3]
+		if !domainEndpoints[parts[3]] {
+			writeError(w, http.StatusNotFound, "unknown endpoint")
+			return "other"
+		}
+		if !pig.ValidDomain(domain) {
+			writeError(w, http.StatusBadRequest, "invalid domain name")
+			return route
+		}
+		switch parts[3] {
+		case "report":
+			s.respond(w, "report "+domain, func() (interface{}, error) {
+				return s.scanner.Report(domain), nil
+			})
+		case "records":
+			s.respond(w, "records "+domain, func() (interface{}, error) {
+				return s.scanner.Records(domain), nil
+			})
+		case "spf":
+			s.respond(w, "spf "+domain, func() (interface{}, error) {
+				return s.scanner.SPF(domain)
+			})
+		case "dnssec":
+			s.respond(w, "dnssec "+domain, func() (interface{}, error) {
+				return s.scanner.DNSSEC(domain), nil
+			})
+		}
+		return route
+	case len(parts) == 3 && parts[0] == "v1" && parts[1] == "ip":
+		ip := net.ParseIP(parts[2])
+		if ip == nil {
+			writeError(w, http.StatusBadRequest, "invalid IP address")
+			return "/v1/ip/{ip}"
+		}
+		if ipv4 := ip.To4(); ipv4 != nil {
+			ip = ipv4
+		}
+		s.respond(w, "ip "+ip.String(), func() (interface{}, error) {
+			return s.scanner.Address(ip), nil
+		})
+		return "/v1/ip/{ip}"
+	default:
+		writeError(w, http.StatusNotFound, "unknown endpoint")
+		return "other"
+	}
+}
+
+// respond serves a cached or freshly computed result. Scans beyond the
+// concurrency limit wait for a free slot within the request timeout.
+func (s *apiServer) respond(w http.ResponseWriter, key string, compute func() (interface{}, error)) {
+	entry := s.cache.get(key, func() (interface{}, error) {
+		select {
+		case s.scans <- struct{}{}:
+		case <-time.After(s.queue):
+			return nil, errBusy
+		}
+		s.metrics.mu.Lock()
+		s.metrics.scans++
+		s.metrics.inFlight++
+		s.metrics.mu.Unlock()
+		defer func() {
+			<-s.scans
+			s.metrics.mu.Lock()
+			s.metrics.inFlight--
+			s.metrics.mu.Unlock()
+		}()
+		return compute()
+	})
+
+	select {
+	case <-entry.done:
+	case <-time.After(s.timeout):
+		writeError(w, http.StatusGatewayTimeout, "timed out, the result will be cached when it completes")
+		return
+	}
+	switch {
+	case entry.err == errBusy:
+		writeError(w, http.StatusServiceUnavailable, entry.err.Error())
+	case entry.err != nil:
+		writeError(w, http.StatusBadGateway, entry.err.Error())
+	default:
+		writeJSON(w, http.StatusOK, entry.value)
+	}
+}
+
+func (s *apiServer) writeMetrics(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	hits, misses, size := s.cache.stats()
+
+	s.metrics.mu.Lock()
+	defer s.metrics.mu.Unlock()
+	routes := make([]string, 0, len(s.metrics.routes))
+	for route := range s.metrics.routes {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+
+	fmt.Fprintln(w, "# HELP pig_http_requests_total HTTP requests by route and status code.")
+	fmt.Fprintln(w, "# TYPE pig_http_requests_total counter")
+	for _, route := range routes {
+		codes := []int{}
+		for code := range s.metrics.routes[route].codes {
+			codes = append(codes, code)
+		}
+		sort.Ints(codes)
+		for _, code := range codes {
+			fmt.Fprintf(w, "pig_http_requests_total{route=%q,code=\"%d\"} %d\n", route, code, s.metrics.routes[route].codes[code])
+		}
+	}
+	fmt.Fprintln(w, "# HELP pig_http_request_duration_seconds Time spent serving HTTP requests.")
+	fmt.Fprintln(w, "# TYPE pig_http_request_duration_seconds summary")
+	for _, route := range routes {
+		m := s.metrics.routes[route]
+		fmt.Fprintf(w, "pig_http_request_duration_seconds_sum{route=%q} %g\n", route, m.duration.Seconds())
+		fmt.Fprintf(w, "pig_http_request_duration_seconds_count{route=%q} %d\n", route, m.count)
+	}
+	fmt.Fprintln(w, "# HELP pig_scans_total Scans started.")
+	fmt.Fprintln(w, "# TYPE pig_scans_total counter")
+	fmt.Fprintf(w, "pig_scans_total %d\n", s.metrics.scans)
+	fmt.Fprintln(w, "# HELP pig_scans_in_flight Scans currently running.")
+	fmt.Fprintln(w, "# TYPE pig_scans_in_flight gauge")
+	fmt.Fprintf(w, "pig_scans_in_flight %d\n", s.metrics.inFlight)
+	fmt.Fprintln(w, "# HELP pig_cache_hits_total Requests answered from the cache.")
+	fmt.Fprintln(w, "# TYPE pig_cache_hits_total counter")
+	fmt.Fprintf(w, "pig_cache_hits_total %d\n", hits)
+	fmt.Fprintln(w, "# HELP pig_cache_misses_total Requests that started a new scan.")
+	fmt.Fprintln(w, "# TYPE pig_cache_misses_total counter")
+	fmt.Fprintf(w, "pig_cache_misses_total %d\n", misses)
+	fmt.Fprintln(w, "# HELP pig_cache_entries Results currently cached.")
+	fmt.Fprintln(w, "# TYPE pig_cache_entries gauge")
+	fmt.Fprintf(w, "pig_cache_entries %d\n", size)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
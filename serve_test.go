@@ -0,0 +1,217 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestResultCacheShared(t *testing.T) {
+	c := newResultCache(time.Minute, 10)
+	release := make(chan struct{})
+	calls := 0
+	compute := func() (interface{}, error) {
+		calls++
+		<-release
+		return "result", nil
+	}
+
+	first := c.get("report example.com", compute)
+	second := c.get("report example.com", compute)
+	if first != second {
+		t.Fatal("concurrent requests for the same key got different entries")
+	}
+	close(release)
+	<-first.done
+	if calls != 1 || first.value != "result" {
+		t.Errorf("compute ran %d times with value %v, want once with result", calls, first.value)
+	}
+	if third := c.get("report example.com", compute); third != first || calls != 1 {
+		t.Errorf("a finished result was computed again")
+	}
+	if hits, misses, size := c.stats(); hits != 2 || misses != 1 || size != 1 {
+		t.Errorf("stats = %d hits, %d misses, %d entries, want 2, 1 and 1", hits, misses, size)
+	}
+}
+
+func TestResultCacheExpiry(t *testing.T) {
+	c := newResultCache(20*time.Millisecond, 10)
+	calls := 0
+	compute := func() (interface{}, error) {
+		calls++
+		return calls, nil
+	}
+	<-c.get("key", compute).done
+	time.Sleep(40 * time.Millisecond)
+	e := c.get("key", compute)
+	<-e.done
+	if calls != 2 || e.value != 2 {
+		t.Errorf("expired result not recomputed: %d calls, value %v", calls, e.value)
+	}
+}
+
+func TestResultCacheErrors(t *testing.T) {
+	c := newResultCache(time.Minute, 10)
+	calls := 0
+	compute := func() (interface{}, error) {
+		calls++
+		return nil, errors.New("lookup failed")
+	}
+	e := c.get("key", compute)
+	<-e.done
+	if e.err == nil {
+		t.Fatal("the error was not returned")
+	}
+	if _, _, size := c.stats(); size != 0 {
+		t.Errorf("a failed result was cached")
+	}
+	<-c.get("key", compute).done
+	if calls != 2 {
+		t.Errorf("compute ran %d times, want a retry after the failure", calls)
+	}
+}
+
+func TestResultCacheEviction(t *testing.T) {
+	c := newResultCache(time.Minute, 1)
+	release := make(chan struct{})
+	pending := c.get("pending", func() (interface{}, error) {
+		<-release
+		return "slow", nil
+	})
+	for _, key := range []string{"a", "b", "c"} {
+		<-c.get(key, func() (interface{}, error) { return key, nil }).done
+	}
+
+	c.mu.Lock()
+	if c.entries["pending"] != pending {
+		t.Error("an entry still being computed was evicted")
+	}
+	if len(c.entries) != 1 {
+		t.Errorf("%d entries cached with room for 1", len(c.entries))
+	}
+	c.mu.Unlock()
+
+	close(release)
+	<-pending.done
+	c.mu.Lock()
+	if len(c.entries) != 1 || c.entries["pending"] != pending {
+		t.Errorf("entries %v after the slow result finished, want only it", c.entries)
+	}
+	c.mu.Unlock()
+}
+
+func testAPIServer(timeout time.Duration, concurrency int) *apiServer {
+	return &apiServer{
+		cache:   newResultCache(time.Minute, 10),
+		metrics: &serverMetrics{routes: map[string]*routeMetrics{}},
+		scans:   make(chan struct{}, concurrency),
+		timeout: timeout,
+		queue:   timeout,
+	}
+}
+
+func TestRespond(t *testing.T) {
+	s := testAPIServer(50*time.Millisecond, 1)
+	w := httptest.NewRecorder()
+	s.respond(w, "ok", func() (interface{}, error) { return map[string]string{"domain": "example.com"}, nil })
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"domain": "example.com"`) {
+		t.Errorf("got %d %s, want the result", w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	s.respond(w, "failed", func() (interface{}, error) { return nil, errors.New("resolver unreachable") })
+	if w.Code != http.StatusBadGateway || !strings.Contains(w.Body.String(), "resolver unreachable") {
+		t.Errorf("got %d %s, want 502 with the error", w.Code, w.Body)
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	w = httptest.NewRecorder()
+	s.respond(w, "slow", func() (interface{}, error) {
+		<-release
+		return "late", nil
+	})
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("a slow scan gave %d, want 504", w.Code)
+	}
+}
+
+func TestRespondBusy(t *testing.T) {
+	s := testAPIServer(time.Second, 1)
+	s.queue = 10 * time.Millisecond
+	s.scans <- struct{}{}
+	w := httptest.NewRecorder()
+	called := false
+	s.respond(w, "queued", func() (interface{}, error) {
+		called = true
+		return nil, nil
+	})
+	if w.Code != http.StatusServiceUnavailable || called {
+		t.Errorf("got %d, want 503 without running the scan", w.Code)
+	}
+	<-s.scans
+
+	// Requests waiting for the same scan share its slot.
+	var wg sync.WaitGroup
+	codes := make([]int, 3)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			s.respond(w, "shared", func() (interface{}, error) {
+				time.Sleep(20 * time.Millisecond)
+				return "done", nil
+			})
+			codes[i] = w.Code
+		}(i)
+	}
+	wg.Wait()
+	for _, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("codes %v, want all 200", codes)
+			break
+		}
+	}
+	if s.metrics.scans != 1 || s.metrics.inFlight != 0 {
+		t.Errorf("%d scans with %d in flight, want 1 and 0", s.metrics.scans, s.metrics.inFlight)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	s := testAPIServer(time.Second, 1)
+	h := s.handler()
+	for _, path := range []string{"/healthz", "/healthz", "/nothing", "/v1/domains/bad..name/report", "/v1/ip/not-an-ip"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	<-s.cache.get("report example.com", func() (interface{}, error) { return "cached", nil }).done
+	s.cache.get("report example.com", nil)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		`pig_http_requests_total{route="/healthz",code="200"} 2`,
+		`pig_http_requests_total{route="other",code="404"} 1`,
+		`pig_http_requests_total{route="/v1/domains/{domain}/report",code="400"} 1`,
+		`pig_http_requests_total{route="/v1/ip/{ip}",code="400"} 1`,
+		`pig_http_request_duration_seconds_count{route="/healthz"} 2`,
+		"# TYPE pig_http_request_duration_seconds summary",
+		"pig_scans_total 0",
+		"pig_scans_in_flight 0",
+		"pig_cache_hits_total 1",
+		"pig_cache_misses_total 1",
+		"pig_cache_entries 1",
+	} {
+		if !strings.Contains(body, want+"\n") {
+			t.Errorf("metrics missing %q:\n%s", want, body)
+		}
+	}
+}
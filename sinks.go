@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/donuts-are-good/pig/pkg/pig"
+)
+
+type alertSink interface {
+	Send(alerts []pig.Alert) error
+}
+
+// newAlertSink parses a sink spec: stdout, syslog, webhook=URL or exec=COMMAND.
+func newAlertSink(spec string) (alertSink, error) {
+	kind, arg, _ := strings.Cut(spec, "=")
+	switch kind {
+	case "stdout":
+		return &writerSink{w: os.Stdout}, nil
+	case "webhook":
+		if arg == "" {
+			return nil, errors.New("webhook sink needs a URL, e.g. webhook=https://example.com/hook")
+		}
+		return &webhookSink{url: arg, client: &http.Client{Timeout: 10 * time.Second}}, nil
+	case "exec":
+		if arg == "" {
+			return nil, errors.New("exec sink needs a command, e.g. exec=/usr/local/bin/notify")
+		}
+		return &execSink{command: arg}, nil
+	case "syslog":
+		return newSyslogSink()
+	default:
+		return nil, fmt.Errorf("unknown alert sink %q", spec)
+	}
+}
+
+type writerSink struct {
+	w io.Writer
+}
+
+func (s *writerSink) Send(alerts []pig.Alert) error {
+	for _, alert := range alerts {
+		if _, err := fmt.Fprintf(s.w, "[Alert] %s %s %s: %s\n", alert.Time.Format(time.RFC3339), alert.Domain, alert.Kind, alert.Message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type webhookPayload struct {
+	Alerts []pig.Alert `json:"alerts"`
+}
+
+// webhookSink POSTs the alerts as JSON and treats any non-2xx status as a
+// failed delivery.
+type webhookSink struct {
+	url    string
+	client *http.Client
+}
+
+func (s *webhookSink) Send(alerts []pig.Alert) error {
+	body, err := json.Marshal(webhookPayload{Alerts: alerts})
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s returned %s", s.url, resp.Status)
+	}
+	return nil
+}
+
+// execSink runs a shell command with the alerts as JSON on stdin.
+type execSink struct {
+	command string
+}
+
+func (s *execSink) Send(alerts []pig.Alert) error {
+	body, err := json.Marshal(webhookPayload{Alerts: alerts})
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command("sh", "-c", s.command)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), fmt.Sprintf("PIG_ALERT_COUNT=%d", len(alerts)))
+	return cmd.Run()
+}
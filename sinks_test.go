@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/donuts-are-good/pig/pkg/pig"
+)
+
+var testAlerts = []pig.Alert{
+	{Domain: "example.com", Kind: "ns", Message: "NS changed", Time: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)},
+	{Domain: "example.com", Kind: "blocklist", Message: "192.0.2.1 listed on Spamhaus ZEN", Time: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)},
+}
+
+func TestWebhookSink(t *testing.T) {
+	var got webhookPayload
+	var contentType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding webhook body: %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	sink, err := newAlertSink("webhook=" + srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Send(testAlerts); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if contentType != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", contentType)
+	}
+	if len(got.Alerts) != len(testAlerts) || got.Alerts[1].Message != testAlerts[1].Message || !got.Alerts[0].Time.Equal(testAlerts[0].Time) {
+		t.Errorf("webhook received %+v, want %+v", got.Alerts, testAlerts)
+	}
+}
+
+func TestWebhookSinkStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	sink, err := newAlertSink("webhook=" + srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Send(testAlerts); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("Send to a failing webhook returned %v, want a 503 error", err)
+	}
+}
+
+func TestExecSink(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "alerts.json")
+	script := filepath.Join(dir, "notify.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho \"$PIG_ALERT_COUNT\" > \"$1.count\"\ncat > \"$1\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	sink, err := newAlertSink("exec=" + script + " " + out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Send(testAlerts); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got webhookPayload
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("script stdin is not JSON: %v", err)
+	}
+	if len(got.Alerts) != len(testAlerts) || got.Alerts[0].Kind != "ns" {
+		t.Errorf("script received %+v, want %+v", got.Alerts, testAlerts)
+	}
+	count, err := os.ReadFile(out + ".count")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(count)) != "2" {
+		t.Errorf("PIG_ALERT_COUNT = %q, want 2", strings.TrimSpace(string(count)))
+	}
+}
+
+func TestExecSinkFailure(t *testing.T) {
+	sink, err := newAlertSink("exec=exit 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Send(testAlerts); err == nil {
+		t.Error("Send with a failing command returned nil")
+	}
+}
+
+func TestNewAlertSinkErrors(t *testing.T) {
+	for _, spec := range []string{"webhook", "webhook=", "exec=", "email=ops@example.com"} {
+		if _, err := newAlertSink(spec); err == nil {
+			t.Errorf("newAlertSink(%q) returned no error", spec)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+//go:build !windows && !plan9
+
+package main
+
+import (
+	"fmt"
+	"log/syslog"
+
+	"github.com/donuts-are-good/pig/pkg/pig"
+)
+
+type syslogSink struct {
+	w *syslog.Writer
+}
+
+func newSyslogSink() (alertSink, error) {
+	w, err := syslog.New(syslog.LOG_WARNING|syslog.LOG_DAEMON, "pig")
+	if err != nil {
+		return nil, err
+	}
+	return &syslogSink{w: w}, nil
+}
+
+func (s *syslogSink) Send(alerts []pig.Alert) error {
+	for _, alert := range alerts {
+		if err := s.w.Warning(fmt.Sprintf("%s %s: %s", alert.Domain, alert.Kind, alert.Message)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
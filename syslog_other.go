@@ -0,0 +1,9 @@
+//go:build windows || plan9
+
+package main
+
+import "errors"
+
+func newSyslogSink() (alertSink, error) {
+	return nil, errors.New("syslog is not available on this platform")
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/donuts-are-good/pig/pkg/pig"
+)
+
+func verifyCommand(args []string) {
+	fs := commandFlags("verify", "domain -zone zonefile")
+	zoneFile := fs.String("zone", "", "zone file with the records the nameservers should serve")
+	var servers stringList
+	fs.Var(&servers, "server", "nameserver to check, as host or host:port (repeatable, default the domain's NS records)")
+	domain := domainArg(fs, parseCommand(fs, args))
+	if *zoneFile == "" {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	zone, err := pig.ParseZoneFile(*zoneFile, domain)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error reading zone file:", err)
+		os.Exit(exitFailure)
+	}
+	for _, e := range zone.Errors {
+		fmt.Fprintln(os.Stderr, "Skipping", e.Error())
+	}
+
+	scanner := newScanner()
+	if scanner.Profile() == pig.Passive {
+		fmt.Fprintln(os.Stderr, "verify queries the domain's nameservers directly and cannot be used with the passive profile.")
+		os.Exit(exitUsage)
+	}
+	v, err := scanner.Verify(domain, zone, servers)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error verifying zone:", err)
+		os.Exit(exitFailure)
+	}
+	if outputFormat == "json" {
+		printJSON(v)
+	} else {
+		printVerification(v)
+		printLookups(v.Lookups)
+	}
+	if v.Differences() > 0 {
+		os.Exit(exitFindings)
+	}
+}
+
+func printVerification(v *pig.Verification) {
+	fmt.Printf("\n[Verify %s against %s]\n", v.Domain, v.File)
+	if v.Skipped > 0 {
+		fmt.Printf("Skipped %d %s below delegations or of types pig cannot compare\n", v.Skipped, plural(v.Skipped, "record", "records"))
+	}
+	if len(v.Servers) < 1 {
+		fmt.Println("No nameservers to check")
+		return
+	}
+	for _, server := range v.Servers {
+		if server.Address != "" {
+			fmt.Printf("\n[%s (%s)]\n", strings.TrimSuffix(server.Server, "."), server.Address)
+		} else {
+			fmt.Printf("\n[%s]\n", strings.TrimSuffix(server.Server, "."))
+		}
+		if server.Error != "" {
+			fmt.Println("Not checked:", server.Error)
+			continue
+		}
+		fmt.Printf("%d of %d RRsets match\n", server.Matched, server.Checked)
+		for _, diff := range server.Diffs {
+			fmt.Printf("-  %s\n", describeDiff(diff))
+		}
+	}
+	fmt.Printf("\n%d %s across %d %s\n", v.Differences(), plural(v.Differences(), "difference", "differences"), len(v.Servers), plural(len(v.Servers), "server", "servers"))
+}
+
+func describeDiff(d pig.RRsetDiff) string {
+	at := ""
+	if d.Line > 0 {
+		at = fmt.Sprintf(" (%s:%d)", d.File, d.Line)
+	}
+	rrset := fmt.Sprintf("%s %s %s%s", strings.ToUpper(string(d.Kind)), strings.TrimSuffix(d.Name, "."), d.Type, at)
+	switch d.Kind {
+	case pig.DiffMissing:
+		return fmt.Sprintf("%s: expected %s", rrset, strings.Join(d.Expected, ", "))
+	case pig.DiffExtra:
+		return fmt.Sprintf("%s: served %s", rrset, strings.Join(d.Actual, ", "))
+	case pig.DiffMismatch:
+		return fmt.Sprintf("%s: expected %s, served %s", rrset, strings.Join(d.Expected, ", "), strings.Join(d.Actual, ", "))
+	case pig.DiffTTL:
+		return fmt.Sprintf("%s: TTL %d, expected %d", rrset, d.ActualTTL, d.ExpectedTTL)
+	}
+	return fmt.Sprintf("%s: %s", rrset, d.Error)
+}
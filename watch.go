@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/donuts-are-good/pig/pkg/pig"
+)
+
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+type watchTarget struct {
+	domain string
+	last   *pig.Report
+	next   time.Time
+}
+
+type namedSink struct {
+	spec string
+	sink alertSink
+}
+
+func watchCommand(args []string) {
+	fs := commandFlags("watch", "-f domains.txt [domain...]")
+	file := fs.String("f", "", "file of domains to watch, one per line")
+	interval := fs.Duration("interval", 15*time.Minute, "time between scans of a domain")
+	maxInterval := fs.Duration("max-interval", 24*time.Hour, "longest wait when record TTLs are longer than -interval")
+	testAlert := fs.Bool("test", false, "send a test alert to every sink and exit")
+	var specs stringList
+	fs.Var(&specs, "sink", "alert sink: stdout, syslog, webhook=URL or exec=COMMAND (repeatable, default stdout)")
+	positional := parseCommand(fs, args)
+
+	domains := []string{}
+	if *file != "" {
+		list, err := readWordlist(*file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error reading domain list:", err)
+			os.Exit(exitFailure)
+		}
+		domains = append(domains, list...)
+	}
+	for _, domain := range positional {
+		domains = append(domains, strings.TrimSuffix(strings.ToLower(domain), "."))
+	}
+	if len(domains) < 1 && !*testAlert {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	if len(specs) < 1 {
+		specs = stringList{"stdout"}
+	}
+	sinks := []namedSink{}
+	for _, spec := range specs {
+		sink, err := newAlertSink(spec)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error configuring alert sink:", err)
+			os.Exit(exitFailure)
+		}
+		sinks = append(sinks, namedSink{spec: spec, sink: sink})
+	}
+
+	if *testAlert {
+		alert := pig.Alert{Domain: "example.com", Kind: "test", Message: "test alert from pig watch", Time: time.Now().UTC()}
+		if failed := deliverAlerts(sinks, []pig.Alert{alert}); failed > 0 {
+			os.Exit(exitFailure)
+		}
+		return
+	}
+
+	scanner := newScanner()
+	store, err := snapshotStore()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error reading snapshots:", err)
+		os.Exit(exitFailure)
+	}
+
+	// Scans pick up from the last saved snapshot so changes made while
+	// pig was not running still raise alerts.
+	targets := []*watchTarget{}
+	for _, domain := range domains {
+		target := &watchTarget{domain: domain, next: time.Now()}
+		if ids, err := store.List(domain); err == nil && len(ids) > 0 {
+			target.last, _ = store.Load(domain, ids[len(ids)-1])
+		}
+		targets = append(targets, target)
+	}
+
+	fmt.Printf("Watching %d domains every %s\n", len(targets), *interval)
+	for {
+		sort.Slice(targets, func(i, j int) bool {
+			return targets[i].next.Before(targets[j].next)
+		})
+		target := targets[0]
+		time.Sleep(time.Until(target.next))
+
+		report := scanner.Report(target.domain)
+		alerts := []pig.Alert{}
+		if target.last != nil {
+			alerts = pig.Alerts(target.last, report)
+		}
+		if snapshotNeeded(target.last, report, alerts) && !noSnapshot {
+			if _, err := store.Save(report); err != nil {
+				fmt.Fprintln(os.Stderr, "Error saving snapshot:", err)
+			}
+		}
+		if len(alerts) > 0 {
+			deliverAlerts(sinks, alerts)
+		}
+		target.last = report
+
+		wait := nextScan(report, *interval, *maxInterval)
+		target.next = time.Now().Add(wait)
+		fmt.Printf("%s scanned %s: %d alerts, next scan in %s\n",
+			time.Now().UTC().Format(time.RFC3339), target.domain, len(alerts), wait)
+	}
+}
+
+// snapshotNeeded reports whether a scan should be saved. Alerts cover
+// changes the diff does not, such as DNSSEC turning bogus, and the state
+// that raised them has to be saved so a restart does not raise them again.
+func snapshotNeeded(last, report *pig.Report, alerts []pig.Alert) bool {
+	return last == nil || len(alerts) > 0 || !pig.Diff(last, report).Empty()
+}
+
+// nextScan waits for the interval, or until the shortest record TTL of the
+// domain expires when that is longer, since the resolver would answer from
+// cache until then.
+func nextScan(report *pig.Report, interval, maxInterval time.Duration) time.Duration {
+	if len(report.Records) < 1 {
+		return interval
+	}
+	shortest := report.Records[0].TTL
+	for _, rec := range report.Records[1:] {
+		if rec.TTL < shortest {
+			shortest = rec.TTL
+		}
+	}
+	wait := time.Duration(shortest) * time.Second
+	if wait < interval {
+		return interval
+	}
+	if wait > maxInterval {
+		return maxInterval
+	}
+	return wait
+}
+
+// deliverAlerts sends the alerts to every sink and returns how many failed.
+func deliverAlerts(sinks []namedSink, alerts []pig.Alert) int {
+	failed := 0
+	for _, s := range sinks {
+		if err := s.sink.Send(alerts); err != nil {
+			fmt.Fprintf(os.Stderr, "Error sending alerts to %s: %v\n", s.spec, err)
+			failed++
+		}
+	}
+	return failed
+}
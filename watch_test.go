@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/donuts-are-good/pig/pkg/pig"
+)
+
+func TestNextScan(t *testing.T) {
+	interval, maxInterval := 15*time.Minute, 2*time.Hour
+	tests := []struct {
+		name string
+		ttls []uint32
+		want time.Duration
+	}{
+		{"no records", nil, interval},
+		{"short ttls", []uint32{60, 300}, interval},
+		{"ttl equal to the interval", []uint32{900}, interval},
+		{"shortest ttl longer than the interval", []uint32{3600, 1800, 7000}, 30 * time.Minute},
+		{"ttl longer than the max interval", []uint32{86400}, maxInterval},
+	}
+	for _, tt := range tests {
+		report := &pig.Report{}
+		for _, ttl := range tt.ttls {
+			report.Records = append(report.Records, pig.Record{Name: "example.com.", Type: "A", TTL: ttl})
+		}
+		if got := nextScan(report, interval, maxInterval); got != tt.want {
+			t.Errorf("%s: nextScan = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSnapshotNeeded(t *testing.T) {
+	report := func(status string) *pig.Report {
+		return &pig.Report{Domain: "example.com", Sections: []string{"dnssec"}, DNSSEC: &pig.DNSSECStatus{Status: status}}
+	}
+	if !snapshotNeeded(nil, report("secure"), nil) {
+		t.Error("the first scan of a domain was not saved")
+	}
+	if snapshotNeeded(report("secure"), report("secure"), nil) {
+		t.Error("an unchanged scan was saved")
+	}
+	// The diff ignores DNSSEC, but the alert it raised must not fire
+	// again after a restart.
+	last, cur := report("secure"), report("bogus")
+	alerts := pig.Alerts(last, cur)
+	if len(alerts) != 1 || !snapshotNeeded(last, cur, alerts) {
+		t.Errorf("a scan raising %+v was not saved", alerts)
+	}
+}